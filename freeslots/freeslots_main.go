@@ -1,7 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"freeslots/utils"
@@ -10,57 +18,413 @@ import (
 )
 
 type InputArgs struct {
-	UserEmail               string `arg:"--useremail,required" help:"Full user email of the requestor. Mandatory field"`
-	ShowAllEvents           bool   `arg:"--showallevents" help:"If present, show all events, otherwise show only free slots among events"`
-	CredentialsFileName     string `arg:"--creds" default:"credentials.json" help:"credentials.json file from Google"`
-	TokenFileName           string `arg:"--token" default:"token.json" help:"token.json file created by this app with the auth token from Google"`
-	WebserverAddressAndPort string `arg:"--listen" default:"localhost:8080" help:"server address and port to open to get token from Google auth process"`
-	NoDays                  int    `arg:"--nodays" default:"14" help:"Number of days after today"`
-	MinDuration             int    `arg:"--minduration" default:"60" help:"Min duration of slots to search for"`
-	FromTime                string `arg:"--from" default:"09:00" help:"From what time to start reporting free slots"`
-	ToTime                  string `arg:"--to" default:"18:00" help:"To what time reporting free slots"`
-	Format                  string `arg:"--format" default:"plain" help:"Output format. Can be: plain, html, markdown"`
-	SkipWeekends            bool   `arg:"--skipweekends" help:"If present, skip weekends"`
-	StartDate               string `arg:"--startdate" default:"" help:"From what date to start reporting free slots. Format accepted: yyyy-MM-dd"`
-	ShowSlotDuration        bool   `arg:"--showslotduration" help:"If present, show the free slot duration"`
+	UserEmail               string            `arg:"--useremail" help:"Full user email of the requestor. Mandatory field when reading events from Google Calendar"`
+	ShowAllEvents           bool              `arg:"--showallevents" help:"If present, show all events, otherwise show only free slots among events"`
+	CredentialsFileName     string            `arg:"--creds" default:"credentials.json" help:"credentials.json file from Google"`
+	TokenFileName           string            `arg:"--token" default:"token.json" help:"token.json file created by this app with the auth token from Google"`
+	WebserverAddressAndPort string            `arg:"--listen" default:"localhost:8080" help:"server address and port to open to get token from Google auth process"`
+	AuthMode                string            `arg:"--authmode" default:"local" help:"How to sign in to Google: local (browser redirected to --listen) or paste (paste the redirected URL)"`
+	ServiceAccountFileName  string            `arg:"--serviceaccount" help:"Service account JSON key to use instead of signing in with --creds and --token"`
+	Subject                 string            `arg:"--subject" help:"With --serviceaccount, email of the Workspace user to impersonate through domain-wide delegation. Default for --useremail"`
+	NoDays                  int               `arg:"--nodays" default:"14" help:"Number of days after today"`
+	MinDuration             int               `arg:"--minduration" default:"60" help:"Min duration of slots to search for"`
+	FromTime                string            `arg:"--from" default:"09:00" help:"From what time to start reporting free slots"`
+	ToTime                  string            `arg:"--to" default:"18:00" help:"To what time reporting free slots"`
+	Format                  string            `arg:"--format" default:"plain" help:"Output format. Can be: plain, html, markdown, json, ics"`
+	SkipWeekends            bool              `arg:"--skipweekends" help:"If present, skip weekends"`
+	StartDate               string            `arg:"--startdate" default:"" help:"From what date to start reporting free slots. Format accepted: yyyy-MM-dd"`
+	ShowSlotDuration        bool              `arg:"--showslotduration" help:"If present, show the free slot duration"`
+	Calendars               []string          `arg:"--calendars" help:"IDs of the calendars to query. Default: primary"`
+	AllCalendars            bool              `arg:"--allcalendars" help:"If present, query all the calendars in the calendar list of the user"`
+	Attendees               []string          `arg:"--attendees" help:"Emails of the attendees, comma separated. If present, show only the free slots shared by all the attendees"`
+	RequiredAttendees       []string          `arg:"--required" help:"Emails of the required attendees, comma separated. Same as --attendees"`
+	OptionalAttendees       []string          `arg:"--optional" help:"Emails of the optional attendees, comma separated. Slots are shown with the optional attendees who are missing"`
+	AttendeeSpecs           []string          `arg:"--attendee" help:"Required attendee with own time zone and working hours, in the form email[@timezone][@HH:MM-HH:MM], e.g. bob@x.com@America/New_York@09:00-17:00. Other attendee options accept the same form"`
+	Quorum                  int               `arg:"--quorum" default:"0" help:"Min number of attendees that must be free in a slot, including required ones. Default: all the attendees"`
+	IcsFileNames            []string          `arg:"--ics" help:"iCalendar (.ics) files to read events from, instead of Google Calendar"`
+	CalDavUrl               string            `arg:"--caldav" help:"URL of a CalDAV calendar to read events from, instead of Google Calendar"`
+	CalDavUserName          string            `arg:"--caldavuser" help:"User name for basic authentication on the CalDAV server"`
+	CalDavPassword          string            `arg:"--caldavpassword,env:CALDAV_PASSWORD" help:"Password for basic authentication on the CalDAV server"`
+	CalDavToken             string            `arg:"--caldavtoken,env:CALDAV_TOKEN" help:"Token for bearer authentication on the CalDAV server"`
+	CalDavFreeBusy          bool              `arg:"--caldavfreebusy" help:"If present, ask the CalDAV server only for busy periods with a free-busy-query"`
+	Source                  string            `arg:"--source" help:"Source of the events. Can be: google, ics, caldav, memory. Default: guessed from the other options, otherwise google"`
+	Agendas                 []string          `arg:"--agenda" help:"Daily agendas for the memory source, e.g. d2025-12-10,m30,s16,aXX--YY"`
+	SourceParameters        map[string]string `arg:"--sourceparam" help:"Parameters for custom sources, in the form key=value"`
+	Timezone                string            `arg:"--timezone" help:"IANA time zone of days, --from/--to and output, e.g. Europe/Rome. Default: local time zone"`
+	WorkingHours            string            `arg:"--hours" help:"Weekly working hours, replacing --from/--to, e.g. mon-thu=08:30-12:30+13:30-17:00,fri=08:30-13:00. Days not listed are skipped"`
+	Blocks                  []string          `arg:"--block" help:"Protected time never reported as free, in the form label[@days]=HH:MM-HH:MM, e.g. lunch=12:30-13:30 or standup@mon-fri=09:30-09:45"`
+	BlocksFileName          string            `arg:"--blocksfile" help:"File with protected blocks, one per line in the same form of --block"`
+	BufferBefore            int               `arg:"--bufferbefore" default:"0" help:"Minutes to keep free before each event"`
+	BufferAfter             int               `arg:"--bufferafter" default:"0" help:"Minutes to keep free after each event"`
+	BufferOnlyWithLocation  bool              `arg:"--bufferonlywithlocation" help:"If present, buffers apply only to events with a location or a video link"`
+	Align                   int               `arg:"--align" default:"0" help:"Round free slots to a grid of minutes, e.g. 15, 30 or 60: starts are rounded up and ends down"`
+	SlotLength              int               `arg:"--slotlength" default:"0" help:"If present, chop free slots into candidate slots of exactly this number of minutes"`
+	AllDayBusy              bool              `arg:"--alldaybusy" help:"If present, all-day events make you busy for the whole day, otherwise they are shown but ignored"`
+	TentativeIsBusy         bool              `arg:"--tentativeisbusy" help:"If present, tentative events and invitations you haven't answered yet make you busy"`
+	IgnoreFocusTime         bool              `arg:"--ignorefocustime" help:"If present, focus time blocks don't make you busy"`
+	Holidays                []string          `arg:"--holidays" help:"Public holidays to skip like weekends: country codes (DE, ES, FR, GB, IT, US) or iCalendar (.ics) files, comma separated"`
+	Profile                 string            `arg:"--profile" help:"Named profile in $XDG_CONFIG_HOME/freeslots, with its own credentials, token and defaults"`
+	List                    *ProfileCommand   `arg:"subcommand:list" help:"List the profiles"`
+	Login                   *ProfileCommand   `arg:"subcommand:login" help:"Sign in to Google. With --profile, also create the profile or save in it the options given"`
+	Logout                  *ProfileCommand   `arg:"subcommand:logout" help:"Revoke the token at Google and delete it"`
+	// options given on the command line, without defaults; with a profile, the others come from it
+	ExplicitArgs *InputArgs `arg:"-"`
 }
 
+// subcommand managing the profiles, with no options of its own
+type ProfileCommand struct{}
+
 func main() {
 	var inputArgs InputArgs
 	arg.MustParse(&inputArgs)
+	if inputArgs.Profile != "" {
+		// parse again without defaults, to tell the options given on the command line
+		var explicitArgs InputArgs
+		parser, err := arg.NewParser(arg.Config{IgnoreDefault: true}, &explicitArgs)
+		if err == nil {
+			err = parser.Parse(os.Args[1:])
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		inputArgs.ExplicitArgs = &explicitArgs
+	}
+	if err := run(inputArgs, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(inputArgs InputArgs, output io.Writer) error {
+	var err error
+	if inputArgs.List != nil {
+		return listProfiles(output)
+	}
+	var profile utils.Profile
+	if inputArgs.Profile != "" {
+		inputArgs, profile, err = applyProfile(inputArgs)
+		if err != nil {
+			return err
+		}
+	}
+	calendarExporterStatus, err := getCalendarExporterStatus(inputArgs)
+	if err != nil {
+		return err
+	}
+	if inputArgs.UserEmail == "" {
+		// the impersonated user is the requestor
+		inputArgs.UserEmail = inputArgs.Subject
+	}
+	if inputArgs.Login != nil {
+		if err := utils.Login(calendarExporterStatus); err != nil {
+			return err
+		}
+		// a failed sign-in leaves no profile behind
+		if inputArgs.Profile != "" {
+			if err := utils.SaveProfile(profile); err != nil {
+				return fmt.Errorf("unable to save profile: %w", err)
+			}
+		}
+		fmt.Fprintln(output, "Logged in")
+		return nil
+	}
+	if inputArgs.Logout != nil {
+		if err := utils.Logout(calendarExporterStatus.TokenFileName); err != nil {
+			return err
+		}
+		fmt.Fprintln(output, "Logged out")
+		return nil
+	}
+
+	location := time.Local
+	if inputArgs.Timezone != "" {
+		location, err = time.LoadLocation(inputArgs.Timezone)
+		if err != nil {
+			return fmt.Errorf("bad time zone: %w", err)
+		}
+	}
+	startDate := utils.GetPureDate(time.Now().In(location))
+	if inputArgs.StartDate != "" {
+		startDate, err = utils.ParseDateInLocation(time.DateOnly, inputArgs.StartDate, location)
+		if err != nil {
+			return fmt.Errorf("bad start date: %w", err)
+		}
+	}
+
+	freeSlotsCoreAlgorithm := utils.FreeSlotsCoreAlgorithm{
+		ShowAllEvents:       inputArgs.ShowAllEvents,
+		NoDays:              inputArgs.NoDays,
+		MinDuration:         inputArgs.MinDuration,
+		FromTime:            inputArgs.FromTime,
+		ToTime:              inputArgs.ToTime,
+		Format:              inputArgs.Format,
+		SkipWeekends:        inputArgs.SkipWeekends,
+		StartDate:           startDate,
+		ShowSlotDuration:    inputArgs.ShowSlotDuration,
+		Quorum:              inputArgs.Quorum,
+		Location:            location,
+		BufferBefore:        inputArgs.BufferBefore,
+		BufferAfter:         inputArgs.BufferAfter,
+		BufferOnlyWithPlace: inputArgs.BufferOnlyWithLocation,
+		AllDayBusy:          inputArgs.AllDayBusy,
+		BusyPolicy:          utils.BusyPolicy{TentativeIsBusy: inputArgs.TentativeIsBusy, IgnoreFocusTime: inputArgs.IgnoreFocusTime},
+		Align:               inputArgs.Align,
+		SlotLength:          inputArgs.SlotLength,
+		Output:              output,
+	}
+	if inputArgs.Align < 0 || (inputArgs.Align > 0 && 24*60%inputArgs.Align != 0) {
+		return fmt.Errorf("bad alignment %v, it must divide a day, e.g. 15, 30 or 60", inputArgs.Align)
+	}
+	if inputArgs.SlotLength < 0 {
+		return fmt.Errorf("bad slot length %v", inputArgs.SlotLength)
+	}
+	if inputArgs.BufferBefore < 0 || inputArgs.BufferAfter < 0 {
+		return fmt.Errorf("bad buffers %v and %v, they must not be negative", inputArgs.BufferBefore, inputArgs.BufferAfter)
+	}
+	if inputArgs.WorkingHours != "" {
+		weeklySchedule, err := utils.ParseWeeklySchedule(inputArgs.WorkingHours)
+		if err != nil {
+			return fmt.Errorf("bad working hours: %w", err)
+		}
+		freeSlotsCoreAlgorithm.Schedule = &weeklySchedule
+	}
+	if len(inputArgs.Holidays) > 0 {
+		freeSlotsCoreAlgorithm.Holidays, err = utils.LoadHolidays(splitCommaSeparatedValues(inputArgs.Holidays), startDate, inputArgs.NoDays)
+		if err != nil {
+			return fmt.Errorf("unable to load holidays: %w", err)
+		}
+	}
+	if inputArgs.BlocksFileName != "" {
+		freeSlotsCoreAlgorithm.Blocks, err = utils.ReadProtectedBlocksFile(inputArgs.BlocksFileName)
+		if err != nil {
+			return fmt.Errorf("unable to read protected blocks: %w", err)
+		}
+	}
+	for _, block := range inputArgs.Blocks {
+		protectedBlock, err := utils.ParseProtectedBlock(block)
+		if err != nil {
+			return err
+		}
+		freeSlotsCoreAlgorithm.Blocks = append(freeSlotsCoreAlgorithm.Blocks, protectedBlock)
+	}
+
+	requiredAttendees := splitCommaSeparatedValues(slices.Concat(inputArgs.Attendees, inputArgs.RequiredAttendees, inputArgs.AttendeeSpecs))
+	optionalAttendees := splitCommaSeparatedValues(inputArgs.OptionalAttendees)
+	attendees := []utils.AttendeeAgenda{}
+	attendeeEmails := []string{}
+	for attendeeIndex, attendee := range append(requiredAttendees, optionalAttendees...) {
+		parsedAttendee, err := utils.ParseAttendee(attendee)
+		if err != nil {
+			return err
+		}
+		parsedAttendee.Optional = attendeeIndex >= len(requiredAttendees)
+		attendees = append(attendees, parsedAttendee)
+		attendeeEmails = append(attendeeEmails, parsedAttendee.Email)
+	}
+	if inputArgs.Quorum < 0 || inputArgs.Quorum > len(attendees) {
+		return fmt.Errorf("bad quorum %v, it must be between 0 (all the attendees) and the number of attendees (%v)", inputArgs.Quorum, len(attendees))
+	}
+	if len(attendees) > 0 {
+		// free/busy information of other people is available only from Google Calendar
+		if eventSourceName := getEventSourceName(inputArgs); eventSourceName != "google" {
+			return fmt.Errorf("attendees can't be combined with the %v source, their free/busy information comes from Google Calendar", eventSourceName)
+		}
+		calendarService, err := utils.CreateCalendarService(calendarExporterStatus)
+		if err != nil {
+			return fmt.Errorf("unable to create Google Calendar service: %w", err)
+		}
+		// one more day on each side, local days of attendees in other time zones can cross the requested range
+		attendeeAgendas, err := utils.GetBusySlotsOfAttendees(calendarService, attendeeEmails, utils.GetPureDateAfterDays(startDate, -1), inputArgs.NoDays+2)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve free/busy information of the attendees: %w", err)
+		}
+		for attendeeIndex := range attendeeAgendas {
+			attendees[attendeeIndex].DailyAgendas = attendeeAgendas[attendeeIndex].DailyAgendas
+		}
+		return freeSlotsCoreAlgorithm.FreeSlotsCoreForAttendees(attendees)
+	}
+
+	eventSourceOptions := utils.EventSourceOptions{
+		CalendarExporterStatus: calendarExporterStatus,
+		UserEmail:              inputArgs.UserEmail,
+		CalendarIds:            splitCommaSeparatedValues(inputArgs.Calendars),
+		AllCalendars:           inputArgs.AllCalendars,
+		IcsFileNames:           inputArgs.IcsFileNames,
+		Agendas:                inputArgs.Agendas,
+		CalDavClient: utils.CalDavClient{
+			CalendarUrl: inputArgs.CalDavUrl,
+			UserName:    inputArgs.CalDavUserName,
+			Password:    inputArgs.CalDavPassword,
+			BearerToken: inputArgs.CalDavToken,
+		},
+		CalDavFreeBusy: inputArgs.CalDavFreeBusy,
+		Parameters:     inputArgs.SourceParameters,
+	}
+	eventSource, err := utils.NewEventSource(getEventSourceName(inputArgs), eventSourceOptions)
+	if err != nil {
+		return err
+	}
+	eventList, err := eventSource.Fetch(context.Background(), startDate, utils.GetPureDateAfterDays(startDate, inputArgs.NoDays))
+	if err != nil {
+		return fmt.Errorf("unable to retrieve events: %w", err)
+	}
+	return freeSlotsCoreAlgorithm.FreeSlotsCore(utils.SplitCalendarEventsByDay(eventList))
+}
+
+// settings to access Google Calendar
+func getCalendarExporterStatus(inputArgs InputArgs) (utils.CalendarExporterStatus, error) {
 	calendarExporterStatus := utils.CalendarExporterStatus{}
+	if inputArgs.AuthMode == "device" {
+		return calendarExporterStatus, fmt.Errorf("authentication mode device is not supported, Google doesn't allow it for calendars: use paste on machines without a browser")
+	}
+	if inputArgs.AuthMode != "" && !slices.Contains(utils.AuthModes, inputArgs.AuthMode) {
+		return calendarExporterStatus, fmt.Errorf("bad authentication mode %q, use one of %v", inputArgs.AuthMode, strings.Join(utils.AuthModes, ", "))
+	}
+	if inputArgs.Subject != "" && inputArgs.ServiceAccountFileName == "" {
+		return calendarExporterStatus, fmt.Errorf("--subject requires --serviceaccount")
+	}
 	calendarExporterStatus.CredentialsFileName = inputArgs.CredentialsFileName
 	calendarExporterStatus.TokenFileName = inputArgs.TokenFileName
 	calendarExporterStatus.WebserverAddressAndPort = inputArgs.WebserverAddressAndPort
+	calendarExporterStatus.AuthMode = inputArgs.AuthMode
+	calendarExporterStatus.ServiceAccountFileName = inputArgs.ServiceAccountFileName
+	calendarExporterStatus.Subject = inputArgs.Subject
+	return calendarExporterStatus, nil
+}
 
-	calendarService, err := utils.CreateCalendarService(calendarExporterStatus)
+// fill the options not given on the command line with the settings of the profile. Logging in adds
+// those given to the returned profile, which is saved once signed in
+func applyProfile(inputArgs InputArgs) (InputArgs, utils.Profile, error) {
+	profilesDir, err := utils.GetProfilesDir()
 	if err != nil {
-		log.Fatalf("Unable to create Google Calendar service: %v", err)
+		return inputArgs, utils.Profile{}, err
+	}
+	profile, err := utils.LoadProfile(profilesDir, inputArgs.Profile)
+	if err != nil && (inputArgs.Login == nil || !errors.Is(err, utils.ErrUnknownProfile)) {
+		return inputArgs, profile, err
+	}
+	explicitArgs := inputArgs
+	if inputArgs.ExplicitArgs != nil {
+		explicitArgs = *inputArgs.ExplicitArgs
+	}
+	if inputArgs.Login != nil {
+		if err := updateProfile(&profile, explicitArgs); err != nil {
+			return inputArgs, profile, err
+		}
 	}
 
-	startDate := utils.GetPureDate(time.Now())
-	if inputArgs.StartDate != "" {
-		startDate, err = time.Parse(time.DateOnly, inputArgs.StartDate)
-		if err != nil {
-			log.Fatalf("Bad start date: %v", err)
+	setFromProfile(&inputArgs.UserEmail, explicitArgs.UserEmail, profile.UserEmail)
+	setFromProfile(&inputArgs.CredentialsFileName, explicitArgs.CredentialsFileName, profile.GetCredentialsFileName())
+	setFromProfile(&inputArgs.TokenFileName, explicitArgs.TokenFileName, profile.GetTokenFileName())
+	setFromProfile(&inputArgs.AuthMode, explicitArgs.AuthMode, profile.AuthMode)
+	setFromProfile(&inputArgs.ServiceAccountFileName, explicitArgs.ServiceAccountFileName, profile.GetServiceAccountFileName())
+	setFromProfile(&inputArgs.Subject, explicitArgs.Subject, profile.Subject)
+	// --hours replaces --from and --to, so the hours of the profile must not override them
+	if explicitArgs.FromTime == "" && explicitArgs.ToTime == "" {
+		setFromProfile(&inputArgs.WorkingHours, explicitArgs.WorkingHours, profile.WorkingHours)
+	}
+	setFromProfile(&inputArgs.Format, explicitArgs.Format, profile.Format)
+	setFromProfile(&inputArgs.Timezone, explicitArgs.Timezone, profile.Timezone)
+	if len(explicitArgs.Calendars) == 0 && len(profile.Calendars) > 0 {
+		inputArgs.Calendars = profile.Calendars
+	}
+	return inputArgs, profile, nil
+}
+
+// use the value of the profile for an option not given on the command line
+func setFromProfile(value *string, explicitValue string, profileValue string) {
+	if explicitValue == "" && profileValue != "" {
+		*value = profileValue
+	}
+}
+
+// save in the profile the options given on the command line, with absolute paths
+func updateProfile(profile *utils.Profile, explicitArgs InputArgs) error {
+	for _, setting := range []struct {
+		profileValue  *string
+		explicitValue string
+		isPath        bool
+	}{
+		{&profile.UserEmail, explicitArgs.UserEmail, false},
+		{&profile.Credentials, explicitArgs.CredentialsFileName, true},
+		{&profile.Token, explicitArgs.TokenFileName, true},
+		{&profile.AuthMode, explicitArgs.AuthMode, false},
+		{&profile.ServiceAccount, explicitArgs.ServiceAccountFileName, true},
+		{&profile.Subject, explicitArgs.Subject, false},
+		{&profile.WorkingHours, explicitArgs.WorkingHours, false},
+		{&profile.Format, explicitArgs.Format, false},
+		{&profile.Timezone, explicitArgs.Timezone, false},
+	} {
+		if setting.explicitValue == "" {
+			continue
+		}
+		*setting.profileValue = setting.explicitValue
+		if setting.isPath {
+			absolutePath, err := filepath.Abs(setting.explicitValue)
+			if err != nil {
+				return err
+			}
+			*setting.profileValue = absolutePath
 		}
-		// TODO: fix time zone
 	}
-	dailyAgendas, err := utils.GetEventsFromPrimaryCalendar(calendarService, startDate, inputArgs.NoDays, inputArgs.UserEmail)
+	if len(explicitArgs.Calendars) > 0 {
+		profile.Calendars = explicitArgs.Calendars
+	}
+	return nil
+}
+
+// print the profiles and tell which ones are logged in
+func listProfiles(output io.Writer) error {
+	profilesDir, err := utils.GetProfilesDir()
 	if err != nil {
-		log.Fatalf("Unable to retrieve Google Calendar events: %v", err)
+		return err
 	}
+	profiles, err := utils.ListProfiles(profilesDir)
+	if err != nil {
+		return err
+	}
+	if len(profiles) == 0 {
+		fmt.Fprintf(output, "No profiles in %v\n", profilesDir)
+	}
+	for _, profile := range profiles {
+		status := "not logged in"
+		if profile.IsLoggedIn() {
+			status = "logged in"
+		}
+		if profile.UserEmail != "" {
+			fmt.Fprintf(output, "%v (%v): %v\n", profile.Name, profile.UserEmail, status)
+		} else {
+			fmt.Fprintf(output, "%v: %v\n", profile.Name, status)
+		}
+	}
+	return nil
+}
 
-	freeSlotsCoreAlgorithm := utils.FreeSlotsCoreAlgorithm{
-		ShowAllEvents:    inputArgs.ShowAllEvents,
-		NoDays:           inputArgs.NoDays,
-		MinDuration:      inputArgs.MinDuration,
-		FromTime:         inputArgs.FromTime,
-		ToTime:           inputArgs.ToTime,
-		Format:           inputArgs.Format,
-		SkipWeekends:     inputArgs.SkipWeekends,
-		StartDate:        startDate,
-		ShowSlotDuration: inputArgs.ShowSlotDuration,
-	}
-	freeSlotsCoreAlgorithm.FreeSlotsCore(dailyAgendas)
+// return the name of the event source, guessing it from the other arguments if --source is missing
+func getEventSourceName(inputArgs InputArgs) string {
+	switch {
+	case inputArgs.Source != "":
+		return inputArgs.Source
+	case len(inputArgs.IcsFileNames) > 0:
+		return "ics"
+	case inputArgs.CalDavUrl != "":
+		return "caldav"
+	case len(inputArgs.Agendas) > 0:
+		return "memory"
+	default:
+		return "google"
+	}
+}
+
+// split values like "a,b" into separate values, so that lists can be given either space or comma separated
+func splitCommaSeparatedValues(values []string) []string {
+	splitValues := []string{}
+	for _, value := range values {
+		for _, splitValue := range strings.Split(value, ",") {
+			splitValue = strings.TrimSpace(splitValue)
+			if splitValue != "" {
+				splitValues = append(splitValues, splitValue)
+			}
+		}
+	}
+	return splitValues
 }
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"freeslots/utils"
+)
+
+func TestRunWithMemorySource(t *testing.T) {
+	inputArgs := InputArgs{
+		Agendas:     []string{"d2025-12-10,m30,s16,aXX--YY", "d2025-12-11,m30,s18,aXXXX"},
+		StartDate:   "2025-12-10",
+		NoDays:      3,
+		MinDuration: 60,
+		FromTime:    "09:00",
+		ToTime:      "18:00",
+		Format:      "plain",
+	}
+	var output bytes.Buffer
+	if err := run(inputArgs, &output); err != nil {
+		t.Errorf("Error while running: %v", err)
+		return
+	}
+	expectedLines := []string{
+		"10 Dec 2025: 09:00-10:00",
+		"11 Dec 2025: 11:00-18:00",
+		"12 Dec 2025: 09:00-18:00",
+	}
+	outputLines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(outputLines) != len(expectedLines) {
+		t.Errorf("Unexpected output: %q", output.String())
+		return
+	}
+	for lineIndex, expectedLine := range expectedLines {
+		if !strings.HasPrefix(outputLines[lineIndex], expectedLine) {
+			t.Errorf("Unexpected line %v: %q", lineIndex, outputLines[lineIndex])
+		}
+	}
+
+	inputArgs.Source = "missing"
+	if err := run(inputArgs, &output); err == nil {
+		t.Errorf("Error expected for an unknown source")
+	}
+
+	// quorum larger than the attendees is checked before querying Google
+	inputArgs.Source = ""
+	inputArgs.Attendees = []string{"a@x.com,b@x.com"}
+	inputArgs.Quorum = 3
+	if err := run(inputArgs, &output); err == nil {
+		t.Errorf("Error expected for a quorum larger than the attendees")
+	}
+	// attendees are looked up only in Google Calendar, the agendas would be ignored
+	inputArgs.Quorum = 0
+	if err := run(inputArgs, &output); err == nil || !strings.Contains(err.Error(), "memory source") {
+		t.Errorf("Error expected for attendees with the memory source: %v", err)
+	}
+	inputArgs.Attendees = nil
+	inputArgs.Quorum = 0
+
+	for _, authMode := range []string{"carrier-pigeon", "device"} {
+		inputArgs.AuthMode = authMode
+		if err := run(inputArgs, &output); err == nil {
+			t.Errorf("Error expected for the authentication mode %v", authMode)
+		}
+	}
+
+	inputArgs.AuthMode = ""
+	inputArgs.Subject = "user@corp.com"
+	if err := run(inputArgs, &output); err == nil {
+		t.Errorf("Error expected for a subject without service account")
+	}
+}
+
+func TestRunWithTimezone(t *testing.T) {
+	icsFileName := filepath.Join(t.TempDir(), "calendar.ics")
+	icsCalendar := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:utc\r\nDTSTART:20251210T150000Z\r\nDTEND:20251210T160000Z\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:floating\r\nDTSTART:20251211T120000\r\nDTEND:20251211T130000\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	if err := os.WriteFile(icsFileName, []byte(icsCalendar), 0o600); err != nil {
+		t.Errorf("Error while writing ics file: %v", err)
+		return
+	}
+	inputArgs := InputArgs{
+		IcsFileNames: []string{icsFileName},
+		StartDate:    "2025-12-10",
+		NoDays:       2,
+		FromTime:     "09:00",
+		ToTime:       "18:00",
+		Format:       "plain",
+		Timezone:     "America/New_York",
+	}
+	var output bytes.Buffer
+	if err := run(inputArgs, &output); err != nil {
+		t.Errorf("Error while running: %v", err)
+		return
+	}
+	// UTC events are shown in New York, floating events are in New York
+	expectedOutput := "10 Dec 2025: 09:00-10:00 EST, 11:00-18:00 EST\n11 Dec 2025: 09:00-12:00 EST, 13:00-18:00 EST\n"
+	if output.String() != expectedOutput {
+		t.Errorf("Unexpected output: %q", output.String())
+	}
+
+	inputArgs.Timezone = "Mars/Olympus_Mons"
+	if err := run(inputArgs, &output); err == nil {
+		t.Errorf("Error expected for an unknown time zone")
+	}
+}
+
+func TestRunWithBuffers(t *testing.T) {
+	// busy 09:30-10:30 and 12:00-13:00, lunch is protected
+	inputArgs := InputArgs{
+		Source:       "memory",
+		Agendas:      []string{"d2025-12-10,m30,s19,aXX---YY"},
+		StartDate:    "2025-12-10",
+		NoDays:       1,
+		MinDuration:  30,
+		FromTime:     "09:00",
+		ToTime:       "18:00",
+		Format:       "plain",
+		Timezone:     "UTC",
+		Blocks:       []string{"lunch=13:00-14:00"},
+		BufferBefore: 15,
+		BufferAfter:  30,
+	}
+	var output bytes.Buffer
+	if err := run(inputArgs, &output); err != nil {
+		t.Errorf("Error while running: %v", err)
+		return
+	}
+	// buffers don't apply to the lunch block
+	expectedOutput := "10 Dec 2025: 11:00-11:45 UTC, 14:00-18:00 UTC\n"
+	if output.String() != expectedOutput {
+		t.Errorf("Unexpected output: %q", output.String())
+	}
+
+	for _, buffers := range [][2]int{{-15, 0}, {0, -5}} {
+		inputArgs.BufferBefore, inputArgs.BufferAfter = buffers[0], buffers[1]
+		if err := run(inputArgs, &output); err == nil {
+			t.Errorf("Error expected for negative buffers %v", buffers)
+		}
+	}
+}
+
+func TestRunWithProfile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	inputArgs := InputArgs{
+		Agendas:     []string{"d2025-12-10,m30,s20,aXX"},
+		StartDate:   "2025-12-10",
+		NoDays:      1,
+		MinDuration: 60,
+		FromTime:    "09:00",
+		ToTime:      "18:00",
+		Format:      "plain",
+		Profile:     "work",
+	}
+	var output bytes.Buffer
+	if err := run(inputArgs, &output); err == nil {
+		t.Errorf("Error expected for an unknown profile")
+	}
+
+	// a failed sign-in doesn't create the profile
+	loginArgs := InputArgs{Profile: "work", WorkingHours: "wed=10:00-13:00", Timezone: "UTC", Login: &ProfileCommand{}}
+	if err := run(loginArgs, &output); err == nil {
+		t.Errorf("Error expected while logging in without credentials")
+	}
+	output.Reset()
+	if err := run(InputArgs{List: &ProfileCommand{}}, &output); err != nil || !strings.HasPrefix(output.String(), "No profiles") {
+		t.Errorf("Unexpected profiles: %q %v", output.String(), err)
+	}
+	profilesDir, _ := utils.GetProfilesDir()
+	profile := utils.Profile{Dir: filepath.Join(profilesDir, "work"), WorkingHours: "wed=10:00-13:00", Timezone: "UTC"}
+	if err := utils.SaveProfile(profile); err != nil {
+		t.Errorf("Error while saving the profile: %v", err)
+		return
+	}
+	output.Reset()
+	if err := run(InputArgs{List: &ProfileCommand{}}, &output); err != nil || output.String() != "work: not logged in\n" {
+		t.Errorf("Unexpected profiles: %q %v", output.String(), err)
+	}
+
+	// working hours and time zone come from the profile, unless given
+	output.Reset()
+	inputArgs.ExplicitArgs = &InputArgs{}
+	if err := run(inputArgs, &output); err != nil {
+		t.Errorf("Error while running: %v", err)
+		return
+	}
+	if output.String() != "10 Dec 2025: 11:00-13:00 UTC\n" {
+		t.Errorf("Unexpected output: %q", output.String())
+	}
+	output.Reset()
+	inputArgs.WorkingHours = "wed=09:00-12:00"
+	inputArgs.ExplicitArgs = &InputArgs{WorkingHours: "wed=09:00-12:00"}
+	if err := run(inputArgs, &output); err != nil {
+		t.Errorf("Error while running: %v", err)
+		return
+	}
+	if output.String() != "10 Dec 2025: 09:00-10:00 UTC, 11:00-12:00 UTC\n" {
+		t.Errorf("Unexpected output: %q", output.String())
+	}
+
+	// --from and --to replace the hours of the profile too
+	output.Reset()
+	inputArgs.WorkingHours = ""
+	inputArgs.FromTime, inputArgs.ToTime = "10:00", "12:00"
+	inputArgs.ExplicitArgs = &InputArgs{FromTime: "10:00", ToTime: "12:00"}
+	if err := run(inputArgs, &output); err != nil {
+		t.Errorf("Error while running: %v", err)
+		return
+	}
+	if output.String() != "10 Dec 2025: 11:00-12:00 UTC\n" {
+		t.Errorf("Unexpected output: %q", output.String())
+	}
+
+	output.Reset()
+	inputArgs.Logout = &ProfileCommand{}
+	if err := run(inputArgs, &output); err == nil {
+		t.Errorf("Error expected while logging out without token")
+	}
+}
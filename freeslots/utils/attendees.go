@@ -0,0 +1,293 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// max number of calendars accepted by a single FreeBusy query
+const maxFreeBusyItems = 50
+
+// busy slots of an attendee. Working hours FromTime/ToTime, in the form "HH:MM", are in Location;
+// the ones of FreeSlotsCoreAlgorithm are used when not set
+type AttendeeAgenda struct {
+	Email        string
+	Optional     bool
+	Location     *time.Location
+	FromTime     string
+	ToTime       string
+	DailyAgendas []DailyAgenda
+}
+
+// parse an attendee in the form email[@timezone][@HH:MM-HH:MM], e.g. bob@x.com@America/New_York@09:00-17:00
+func ParseAttendee(attendee string) (AttendeeAgenda, error) {
+	parts := strings.Split(attendee, "@")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return AttendeeAgenda{}, fmt.Errorf("bad attendee %q, expected email[@timezone][@HH:MM-HH:MM]", attendee)
+	}
+	attendeeAgenda := AttendeeAgenda{Email: parts[0] + "@" + parts[1]}
+	for _, part := range parts[2:] {
+		if fromTime, toTime, found := strings.Cut(part, "-"); found && strings.Contains(part, ":") {
+			// working hours
+			for _, workingTime := range []string{fromTime, toTime} {
+				if _, err := time.Parse("15:04", workingTime); err != nil {
+					return AttendeeAgenda{}, fmt.Errorf("attendee %s: bad working hours %q", attendeeAgenda.Email, part)
+				}
+			}
+			attendeeAgenda.FromTime, attendeeAgenda.ToTime = fromTime, toTime
+			continue
+		}
+		location, err := time.LoadLocation(part)
+		if err != nil {
+			return AttendeeAgenda{}, fmt.Errorf("attendee %s: %w", attendeeAgenda.Email, err)
+		}
+		attendeeAgenda.Location = location
+	}
+	return attendeeAgenda, nil
+}
+
+// true if the attendee has working hours or a time zone different from the ones of FreeSlotsCoreAlgorithm
+func (attendeeAgenda AttendeeAgenda) HasOwnWorkingHours() bool {
+	return attendeeAgenda.Location != nil || attendeeAgenda.FromTime != ""
+}
+
+// Get busy slots of a list of attendees from the Google Calendar FreeBusy API.
+// One agenda is returned per attendee, in the same order of the input list
+func GetBusySlotsOfAttendees(srv *calendar.Service, attendeeEmails []string, tMin time.Time, noDays int) ([]AttendeeAgenda, error) {
+	tMinAsString := tMin.Format(time.RFC3339)
+	tMaxAsString := GetPureDateAfterDays(tMin, noDays).Format(time.RFC3339)
+	attendeeAgendas := make([]AttendeeAgenda, 0, len(attendeeEmails))
+	for firstIndex := 0; firstIndex < len(attendeeEmails); firstIndex += maxFreeBusyItems {
+		lastIndex := min(firstIndex+maxFreeBusyItems, len(attendeeEmails))
+		freeBusyRequest := &calendar.FreeBusyRequest{
+			TimeMin: tMinAsString,
+			TimeMax: tMaxAsString,
+			Items:   []*calendar.FreeBusyRequestItem{},
+		}
+		for _, attendeeEmail := range attendeeEmails[firstIndex:lastIndex] {
+			freeBusyRequest.Items = append(freeBusyRequest.Items, &calendar.FreeBusyRequestItem{Id: attendeeEmail})
+		}
+		freeBusyResponse, err := srv.Freebusy.Query(freeBusyRequest).Do()
+		if err != nil {
+			return nil, err
+		}
+		for _, attendeeEmail := range attendeeEmails[firstIndex:lastIndex] {
+			freeBusyCalendar, found := freeBusyResponse.Calendars[attendeeEmail]
+			if !found {
+				return nil, fmt.Errorf("attendee %s: no free/busy information returned", attendeeEmail)
+			}
+			if len(freeBusyCalendar.Errors) > 0 {
+				return nil, fmt.Errorf("attendee %s: %s", attendeeEmail, freeBusyCalendar.Errors[0].Reason)
+			}
+			eventList, err := ConvertTimePeriodsToEvents(freeBusyCalendar.Busy, attendeeEmail, tMin.Location())
+			if err != nil {
+				return nil, fmt.Errorf("attendee %s: %w", attendeeEmail, err)
+			}
+			attendeeAgendas = append(attendeeAgendas, AttendeeAgenda{
+				Email:        attendeeEmail,
+				DailyAgendas: SplitCalendarEventsByDay(eventList),
+			})
+		}
+	}
+	return attendeeAgendas, nil
+}
+
+// convert busy periods returned by the FreeBusy API into calendar events sorted by start time.
+// Busy periods are returned in UTC, they are converted to the given location
+func ConvertTimePeriodsToEvents(timePeriods []*calendar.TimePeriod, attendeeEmail string, location *time.Location) ([]CalendarEvent, error) {
+	eventList := []CalendarEvent{}
+	for _, timePeriod := range timePeriods {
+		startTime, err := time.Parse(time.RFC3339, timePeriod.Start)
+		if err != nil {
+			return nil, err
+		}
+		endTime, err := time.Parse(time.RFC3339, timePeriod.End)
+		if err != nil {
+			return nil, err
+		}
+		startTime = startTime.In(location)
+		eventList = append(eventList, CalendarEvent{
+			StartTime:   startTime,
+			Duration:    int(endTime.Sub(startTime).Minutes()),
+			Description: attendeeEmail,
+			Timezone:    startTime.Location().String(),
+			CalendarId:  attendeeEmail,
+		})
+	}
+	SortEventListByStartTime(&eventList)
+	return eventList, nil
+}
+
+// intersect the free slots of two agendas of the same day, keeping the descriptions of the first one
+// Assumption: free slots of each agenda are sorted and don't overlap
+func IntersectFreeSlots(firstAgenda, secondAgenda DailyAgenda) DailyAgenda {
+	intersectedAgenda := DailyAgenda{
+		Date:   firstAgenda.Date,
+		Events: []CalendarEvent{},
+	}
+	firstIndex, secondIndex := 0, 0
+	for firstIndex < len(firstAgenda.Events) && secondIndex < len(secondAgenda.Events) {
+		firstSlot := firstAgenda.Events[firstIndex]
+		secondSlot := secondAgenda.Events[secondIndex]
+		startTime := firstSlot.StartTime
+		if secondSlot.StartTime.After(startTime) {
+			startTime = secondSlot.StartTime
+		}
+		endTime := firstSlot.GetEndTime()
+		if secondSlot.GetEndTime().Before(endTime) {
+			endTime = secondSlot.GetEndTime()
+		}
+		if endTime.After(startTime) {
+			intersectedAgenda.Events = append(intersectedAgenda.Events, CalendarEvent{
+				StartTime:   startTime,
+				Duration:    int(endTime.Sub(startTime).Minutes()),
+				Description: firstSlot.Description,
+				Timezone:    startTime.Location().String(),
+			})
+		}
+		// move forward the slot that ends first
+		if firstSlot.GetEndTime().Before(secondSlot.GetEndTime()) {
+			firstIndex++
+		} else {
+			secondIndex++
+		}
+	}
+	return intersectedAgenda
+}
+
+// remove free slots shorter than minDuration
+func (dailyAgenda DailyAgenda) FilterByMinDuration(minDuration int) DailyAgenda {
+	filteredAgenda := DailyAgenda{
+		Date:   dailyAgenda.Date,
+		Events: []CalendarEvent{},
+	}
+	for _, event := range dailyAgenda.Events {
+		if event.Duration >= minDuration {
+			filteredAgenda.Events = append(filteredAgenda.Events, event)
+		}
+	}
+	return filteredAgenda
+}
+
+// start or end of a free slot of an attendee, used by the sweep line in SweepFreeSlots
+type freeSlotBoundary struct {
+	time          time.Time
+	attendeeIndex int
+	isStart       bool
+}
+
+// scan the free slots of several attendees on the same day with a sweep line, counting how many
+// attendees are free at any time. It returns the slots where all the required attendees and at least
+// quorum attendees are free. The description of each slot lists the optional attendees who are missing
+// Assumption: freeSlotsAgendas[i] contains the free slots of attendeeAgendas[i], which don't overlap
+func SweepFreeSlots(freeSlotsAgendas []DailyAgenda, attendeeAgendas []AttendeeAgenda, quorum int) DailyAgenda {
+	sweptAgenda := DailyAgenda{
+		Events: []CalendarEvent{},
+	}
+	if len(freeSlotsAgendas) == 0 {
+		return sweptAgenda
+	}
+	sweptAgenda.Date = freeSlotsAgendas[0].Date
+	boundaries := []freeSlotBoundary{}
+	for attendeeIndex, freeSlotsAgenda := range freeSlotsAgendas {
+		for _, freeSlot := range freeSlotsAgenda.Events {
+			boundaries = append(boundaries,
+				freeSlotBoundary{time: freeSlot.StartTime, attendeeIndex: attendeeIndex, isStart: true},
+				freeSlotBoundary{time: freeSlot.GetEndTime(), attendeeIndex: attendeeIndex, isStart: false})
+		}
+	}
+	slices.SortFunc(boundaries, func(a, b freeSlotBoundary) int {
+		return a.time.Compare(b.time)
+	})
+
+	// free slots of each attendee open at the current time, counted so that the order of the end and
+	// the start of adjacent slots doesn't matter
+	noOpenSlots := make([]int, len(attendeeAgendas))
+	for boundaryIndex := 0; boundaryIndex < len(boundaries); {
+		// apply all the boundaries at the same time before evaluating the following segment
+		segmentStartTime := boundaries[boundaryIndex].time
+		for boundaryIndex < len(boundaries) && boundaries[boundaryIndex].time.Equal(segmentStartTime) {
+			if boundaries[boundaryIndex].isStart {
+				noOpenSlots[boundaries[boundaryIndex].attendeeIndex]++
+			} else {
+				noOpenSlots[boundaries[boundaryIndex].attendeeIndex]--
+			}
+			boundaryIndex++
+		}
+		if boundaryIndex == len(boundaries) {
+			break
+		}
+		segmentEndTime := boundaries[boundaryIndex].time
+
+		noFreeAttendees := 0
+		allRequiredAreFree := true
+		missingAttendees := []string{}
+		for attendeeIndex, attendeeAgenda := range attendeeAgendas {
+			switch {
+			case noOpenSlots[attendeeIndex] > 0:
+				noFreeAttendees++
+			case attendeeAgenda.Optional:
+				missingAttendees = append(missingAttendees, attendeeAgenda.Email)
+			default:
+				allRequiredAreFree = false
+			}
+		}
+		if !allRequiredAreFree || noFreeAttendees < quorum {
+			continue
+		}
+		description := "*"
+		if len(missingAttendees) > 0 {
+			description = "missing: " + strings.Join(missingAttendees, ", ")
+		}
+		segmentDuration := int(segmentEndTime.Sub(segmentStartTime).Minutes())
+		lastIndex := len(sweptAgenda.Events) - 1
+		if lastIndex >= 0 && sweptAgenda.Events[lastIndex].GetEndTime().Equal(segmentStartTime) &&
+			sweptAgenda.Events[lastIndex].Description == description {
+			// same attendees as the previous slot, let's glue them
+			sweptAgenda.Events[lastIndex].Duration += segmentDuration
+			continue
+		}
+		sweptAgenda.Events = append(sweptAgenda.Events, CalendarEvent{
+			StartTime:   segmentStartTime,
+			Duration:    segmentDuration,
+			Description: description,
+			Timezone:    segmentStartTime.Location().String(),
+		})
+	}
+	return sweptAgenda
+}
+
+// add to the description of each slot its local time for every attendee with a time zone,
+// e.g. "bob@x.com 09:00-10:00 EST". Descriptions other than "*" are kept
+func AddLocalTimesOfAttendees(freeSlotsAgendas []DailyAgenda, attendeeAgendas []AttendeeAgenda) []DailyAgenda {
+	annotatedAgendas := make([]DailyAgenda, 0, len(freeSlotsAgendas))
+	for _, freeSlotsAgenda := range freeSlotsAgendas {
+		annotatedAgenda := DailyAgenda{
+			Date:   freeSlotsAgenda.Date,
+			Events: make([]CalendarEvent, 0, len(freeSlotsAgenda.Events)),
+		}
+		for _, freeSlot := range freeSlotsAgenda.Events {
+			localTimes := []string{}
+			for _, attendeeAgenda := range attendeeAgendas {
+				if attendeeAgenda.Location == nil {
+					continue
+				}
+				localTimes = append(localTimes, fmt.Sprintf("%s %s-%s", attendeeAgenda.Email,
+					freeSlot.StartTime.In(attendeeAgenda.Location).Format("15:04"),
+					freeSlot.GetEndTime().In(attendeeAgenda.Location).Format("15:04 MST")))
+			}
+			if freeSlot.Description == "*" || freeSlot.Description == "" {
+				freeSlot.Description = strings.Join(localTimes, ", ")
+			} else {
+				freeSlot.Description += "; " + strings.Join(localTimes, ", ")
+			}
+			annotatedAgenda.Events = append(annotatedAgenda.Events, freeSlot)
+		}
+		annotatedAgendas = append(annotatedAgendas, annotatedAgenda)
+	}
+	return annotatedAgendas
+}
@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetCommonFreeSlots(t *testing.T) {
+	attendeeAgendasList := [][]string{
+		{"d2025-12-10,m30,s16,aXX--XX", "d2025-12-10,m30,s16,a--XX--YY"},
+		{"d2025-12-10,m30,s16,aXXX", "d2025-12-10,m30,s16,a-----X"},
+		{"d2025-12-10,m30,s16,aXXX", "d2025-12-10,m30,s16,a-----X", "d2025-12-10,m30,s16,a"},
+		{"d2025-12-10,m30,s16,aXXXXXXXXXXXXXXXXXXXX", "d2025-12-10,m30,s16,a"},
+	}
+	minDurations := []int{30, 30, 90, 30}
+	expectedAgendas := []string{
+		"d2025-12-10,m30,s24,a************",
+		"d2025-12-10,m30,s19,a**-**************",
+		"d2025-12-10,m30,s22,a**************",
+		"d2025-12-10,m30,s16,a",
+	}
+	for agendasIndex, agendas := range attendeeAgendasList {
+		attendeeAgendas := []AttendeeAgenda{}
+		for _, agenda := range agendas {
+			dailyAgenda, _ := ParseDailyAgenda(agenda)
+			attendeeAgendas = append(attendeeAgendas, AttendeeAgenda{
+				Email:        agenda,
+				DailyAgendas: []DailyAgenda{dailyAgenda},
+			})
+		}
+		freeSlotsCoreAlgorithm := FreeSlotsCoreAlgorithm{
+			NoDays:      1,
+			MinDuration: minDurations[agendasIndex],
+			FromTime:    "08:00",
+			ToTime:      "18:00",
+			StartDate:   time.Date(2025, time.December, 10, 0, 0, 0, 0, time.Local),
+		}
+		outputAgendas, err := freeSlotsCoreAlgorithm.GetCommonFreeSlots(attendeeAgendas)
+		if err != nil {
+			t.Errorf("Error while intersecting: agendas index %v, error %v", agendasIndex, err)
+			return
+		}
+		expectedAgenda, _ := ParseDailyAgenda(expectedAgendas[agendasIndex])
+		if len(outputAgendas) != 1 || len(outputAgendas[0].Events) != len(expectedAgenda.Events) {
+			t.Errorf("Error while intersecting: agendas index %v, agendas %v. Different lengths", agendasIndex, agendas)
+			for _, outputAgenda := range outputAgendas {
+				PrintEventList(outputAgenda.Events)
+			}
+			return
+		}
+		for eventIndex, expectedEvent := range expectedAgenda.Events {
+			outputEvent := outputAgendas[0].Events[eventIndex]
+			if outputEvent.Duration != expectedEvent.Duration ||
+				outputEvent.Description != expectedEvent.Description ||
+				outputEvent.StartTime.Hour() != expectedEvent.StartTime.Hour() ||
+				outputEvent.StartTime.Minute() != expectedEvent.StartTime.Minute() {
+				t.Errorf("Error while intersecting: agendas index %v, agendas %v, mismatching event index %v",
+					agendasIndex, agendas, eventIndex)
+				return
+			}
+		}
+	}
+}
+
+func TestGetQuorumFreeSlots(t *testing.T) {
+	day := time.Date(2025, time.December, 10, 0, 0, 0, 0, time.Local)
+	agendas := []string{
+		"d2025-12-10,m30,s16,aXXXX",
+		"d2025-12-10,m30,s16,a--------XX",
+		"d2025-12-10,m30,s16,a----XX",
+	}
+	attendeeAgendas := []AttendeeAgenda{}
+	for agendaIndex, agenda := range agendas {
+		dailyAgenda, _ := ParseDailyAgenda(agenda)
+		attendeeAgendas = append(attendeeAgendas, AttendeeAgenda{
+			Email:        string(rune('A' + agendaIndex)),
+			Optional:     agendaIndex > 0,
+			DailyAgendas: []DailyAgenda{dailyAgenda},
+		})
+	}
+	quorums := []int{2, 3, 0}
+	expectedEventLists := [][]CalendarEvent{
+		{
+			CreateDefaultCalendarEventFromString(day, "10:00", 60, "missing: C"),
+			CreateDefaultCalendarEventFromString(day, "11:00", 60, "*"),
+			CreateDefaultCalendarEventFromString(day, "12:00", 60, "missing: B"),
+			CreateDefaultCalendarEventFromString(day, "13:00", 300, "*"),
+		},
+		{
+			CreateDefaultCalendarEventFromString(day, "11:00", 60, "*"),
+			CreateDefaultCalendarEventFromString(day, "13:00", 300, "*"),
+		},
+		{
+			CreateDefaultCalendarEventFromString(day, "11:00", 60, "*"),
+			CreateDefaultCalendarEventFromString(day, "13:00", 300, "*"),
+		},
+	}
+	for quorumIndex, quorum := range quorums {
+		freeSlotsCoreAlgorithm := FreeSlotsCoreAlgorithm{
+			NoDays:      1,
+			MinDuration: 30,
+			FromTime:    "08:00",
+			ToTime:      "18:00",
+			StartDate:   day,
+			Quorum:      quorum,
+		}
+		outputAgendas, err := freeSlotsCoreAlgorithm.GetQuorumFreeSlots(attendeeAgendas)
+		if err != nil {
+			t.Errorf("Error while sweeping: quorum %v, error %v", quorum, err)
+			return
+		}
+		expectedEvents := expectedEventLists[quorumIndex]
+		if len(outputAgendas) != 1 || len(outputAgendas[0].Events) != len(expectedEvents) {
+			t.Errorf("Error while sweeping: quorum %v. Different lengths", quorum)
+			for _, outputAgenda := range outputAgendas {
+				PrintEventList(outputAgenda.Events)
+			}
+			return
+		}
+		for eventIndex, expectedEvent := range expectedEvents {
+			outputEvent := outputAgendas[0].Events[eventIndex]
+			if outputEvent.Duration != expectedEvent.Duration ||
+				outputEvent.Description != expectedEvent.Description ||
+				outputEvent.StartTime.Hour() != expectedEvent.StartTime.Hour() ||
+				outputEvent.StartTime.Minute() != expectedEvent.StartTime.Minute() {
+				t.Errorf("Error while sweeping: quorum %v, mismatching event index %v", quorum, eventIndex)
+				PrintEventList(outputAgendas[0].Events)
+				return
+			}
+		}
+	}
+}
+
+func TestSweepAdjacentFreeSlots(t *testing.T) {
+	// adjacent free slots of the same attendee, e.g. meeting at midnight in the attendee's time zone,
+	// with enough boundaries to make the order of equal times depend on the sort
+	day := time.Date(2025, time.December, 10, 0, 0, 0, 0, time.Local)
+	freeSlotsAgendas := []DailyAgenda{}
+	attendeeAgendas := []AttendeeAgenda{}
+	for attendeeIndex := 0; attendeeIndex < 10; attendeeIndex++ {
+		freeSlotsAgendas = append(freeSlotsAgendas, DailyAgenda{Date: day, Events: []CalendarEvent{
+			CreateDefaultCalendarEventFromString(day, "09:00", 60, ""),
+			CreateDefaultCalendarEventFromString(day, "10:00", 60, ""),
+		}})
+		attendeeAgendas = append(attendeeAgendas, AttendeeAgenda{Email: string(rune('A' + attendeeIndex))})
+	}
+	sweptAgenda := SweepFreeSlots(freeSlotsAgendas, attendeeAgendas, 10)
+	if len(sweptAgenda.Events) != 1 || sweptAgenda.Events[0].StartTime.Format("15:04") != "09:00" ||
+		sweptAgenda.Events[0].Duration != 120 {
+		t.Errorf("Adjacent free slots not swept together")
+		PrintEventList(sweptAgenda.Events)
+	}
+}
+
+func TestParseAttendee(t *testing.T) {
+	attendees := []string{
+		"alice@x.com",
+		"bob@x.com@America/New_York@09:00-17:00",
+		"carol@x.com@10:00-16:30",
+		"dave@x.com@Asia/Kolkata",
+	}
+	expectedLocations := []string{"", "America/New_York", "", "Asia/Kolkata"}
+	expectedWorkingHours := []string{"-", "09:00-17:00", "10:00-16:30", "-"}
+	for attendeeIndex, attendee := range attendees {
+		attendeeAgenda, err := ParseAttendee(attendee)
+		if err != nil {
+			t.Errorf("Error while parsing attendee %v: %v", attendee, err)
+			continue
+		}
+		location := ""
+		if attendeeAgenda.Location != nil {
+			location = attendeeAgenda.Location.String()
+		}
+		if attendeeAgenda.Email != strings.SplitN(attendee, "@", 3)[0]+"@x.com" ||
+			location != expectedLocations[attendeeIndex] ||
+			attendeeAgenda.FromTime+"-"+attendeeAgenda.ToTime != expectedWorkingHours[attendeeIndex] {
+			t.Errorf("Error while parsing attendee %v: %+v", attendee, attendeeAgenda)
+		}
+	}
+	for _, attendee := range []string{"alice", "bob@x.com@Mars/Olympus_Mons", "carol@x.com@9-17:00"} {
+		if _, err := ParseAttendee(attendee); err == nil {
+			t.Errorf("Error expected while parsing attendee %v", attendee)
+		}
+	}
+}
+
+func TestFreeSlotsCoreForAttendeesInTimeZones(t *testing.T) {
+	rome, _ := time.LoadLocation("Europe/Rome")
+	newYork, _ := time.LoadLocation("America/New_York")
+	london, _ := time.LoadLocation("Europe/London")
+	// bob is busy 10:00-11:00 in New York, 16:00-17:00 in Rome
+	bobAgenda, _ := ParseDailyAgendaInLocation("d2025-12-10,m60,s10,aX", newYork)
+	attendeeAgendas := []AttendeeAgenda{
+		{Email: "alice@x.com"},
+		{Email: "bob@x.com", Location: newYork, FromTime: "09:00", ToTime: "17:00", DailyAgendas: []DailyAgenda{bobAgenda}},
+		{Email: "carol@x.com", Location: london, FromTime: "09:00", ToTime: "17:00"},
+	}
+	var output bytes.Buffer
+	freeSlotsCoreAlgorithm := FreeSlotsCoreAlgorithm{
+		NoDays:    1,
+		FromTime:  "09:00",
+		ToTime:    "18:00",
+		Format:    "plain",
+		StartDate: time.Date(2025, time.December, 10, 0, 0, 0, 0, rome),
+		Output:    &output,
+	}
+	if err := freeSlotsCoreAlgorithm.FreeSlotsCoreForAttendees(attendeeAgendas); err != nil {
+		t.Errorf("Error while getting free slots: %v", err)
+		return
+	}
+	// working hours overlap between 15:00 and 18:00 in Rome
+	expectedOutput := "10 Dec 2025: 15:00-16:00 CET (bob@x.com 09:00-10:00 EST, carol@x.com 14:00-15:00 GMT), " +
+		"17:00-18:00 CET (bob@x.com 11:00-12:00 EST, carol@x.com 16:00-17:00 GMT)\n"
+	if output.String() != expectedOutput {
+		t.Errorf("Unexpected output: %q", output.String())
+	}
+}
@@ -0,0 +1,45 @@
+package utils
+
+// values of the attributes of CalendarEvent, as in the Google Calendar API. Empty values are unknown
+const (
+	StatusConfirmed = "confirmed"
+	StatusTentative = "tentative"
+	StatusCancelled = "cancelled"
+
+	TransparencyOpaque      = "opaque"
+	TransparencyTransparent = "transparent"
+
+	EventTypeDefault         = "default"
+	EventTypeOutOfOffice     = "outOfOffice"
+	EventTypeFocusTime       = "focusTime"
+	EventTypeWorkingLocation = "workingLocation"
+
+	ResponseNeedsAction = "needsAction"
+	ResponseDeclined    = "declined"
+	ResponseTentative   = "tentative"
+	ResponseAccepted    = "accepted"
+)
+
+// decide which events make the user busy. Events marked as free, cancelled or declined ones and
+// working locations never do, out of office always does. Tentative events and invitations without
+// an answer are busy only if TentativeIsBusy, focus time unless IgnoreFocusTime
+type BusyPolicy struct {
+	TentativeIsBusy bool
+	IgnoreFocusTime bool
+}
+
+func (busyPolicy BusyPolicy) IsBusy(event CalendarEvent) bool {
+	switch {
+	case event.EventType == EventTypeOutOfOffice:
+		return true
+	case event.Status == StatusCancelled || event.Transparency == TransparencyTransparent ||
+		event.ResponseStatus == ResponseDeclined || event.EventType == EventTypeWorkingLocation:
+		return false
+	case event.EventType == EventTypeFocusTime:
+		return !busyPolicy.IgnoreFocusTime
+	case event.Status == StatusTentative || event.ResponseStatus == ResponseTentative ||
+		event.ResponseStatus == ResponseNeedsAction:
+		return busyPolicy.TentativeIsBusy
+	}
+	return true
+}
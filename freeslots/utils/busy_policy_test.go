@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+func TestBusyPolicy(t *testing.T) {
+	testCases := []struct {
+		event           CalendarEvent
+		busy            bool
+		tentativeIsBusy bool
+		ignoreFocusTime bool
+	}{
+		{CalendarEvent{}, true, false, true},
+		{CalendarEvent{Status: StatusConfirmed, Transparency: TransparencyOpaque, EventType: EventTypeDefault, ResponseStatus: ResponseAccepted}, true, true, true},
+		{CalendarEvent{Transparency: TransparencyTransparent}, false, false, false},
+		{CalendarEvent{Status: StatusCancelled}, false, false, false},
+		{CalendarEvent{ResponseStatus: ResponseDeclined}, false, false, false},
+		{CalendarEvent{EventType: EventTypeWorkingLocation}, false, false, false},
+		{CalendarEvent{EventType: EventTypeOutOfOffice, Transparency: TransparencyTransparent}, true, true, true},
+		{CalendarEvent{EventType: EventTypeFocusTime}, true, true, false},
+		{CalendarEvent{EventType: EventTypeFocusTime}, false, true, true},
+		{CalendarEvent{ResponseStatus: ResponseNeedsAction}, false, false, false},
+		{CalendarEvent{ResponseStatus: ResponseNeedsAction}, true, true, false},
+		{CalendarEvent{ResponseStatus: ResponseTentative}, false, false, false},
+		{CalendarEvent{Status: StatusTentative}, true, true, false},
+	}
+	for testIndex, testCase := range testCases {
+		busyPolicy := BusyPolicy{TentativeIsBusy: testCase.tentativeIsBusy, IgnoreFocusTime: testCase.ignoreFocusTime}
+		if busyPolicy.IsBusy(testCase.event) != testCase.busy {
+			t.Errorf("Test case %v: busy should be %v", testIndex, testCase.busy)
+		}
+	}
+}
+
+const testGoogleEvents = `{
+ "items": [
+  {"summary": "Accepted", "status": "confirmed", "start": {"dateTime": "2025-12-10T09:00:00+01:00"}, "end": {"dateTime": "2025-12-10T10:00:00+01:00"},
+   "attendees": [{"email": "me@x.com", "self": true, "responseStatus": "accepted"}]},
+  {"summary": "Declined", "status": "confirmed", "start": {"dateTime": "2025-12-10T10:00:00+01:00"}, "end": {"dateTime": "2025-12-10T11:00:00+01:00"},
+   "attendees": [{"email": "me@x.com", "responseStatus": "declined"}]},
+  {"summary": "Not answered", "status": "confirmed", "start": {"dateTime": "2025-12-10T11:00:00+01:00"}, "end": {"dateTime": "2025-12-10T12:00:00+01:00"},
+   "attendees": [{"email": "me@x.com", "self": true, "responseStatus": "needsAction"}]},
+  {"summary": "Show as free", "status": "confirmed", "transparency": "transparent", "start": {"dateTime": "2025-12-10T12:00:00+01:00"}, "end": {"dateTime": "2025-12-10T13:00:00+01:00"}},
+  {"summary": "Cancelled", "status": "cancelled", "start": {"dateTime": "2025-12-10T13:00:00+01:00"}, "end": {"dateTime": "2025-12-10T14:00:00+01:00"}},
+  {"summary": "Focus", "status": "confirmed", "eventType": "focusTime", "start": {"dateTime": "2025-12-10T14:00:00+01:00"}, "end": {"dateTime": "2025-12-10T16:00:00+01:00"}},
+  {"summary": "Office", "status": "confirmed", "eventType": "workingLocation", "transparency": "transparent", "start": {"date": "2025-12-10"}, "end": {"date": "2025-12-11"}}
+ ]
+}`
+
+func TestGoogleEventAttributes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(testGoogleEvents))
+	}))
+	defer server.Close()
+	ctx := context.Background()
+	srv, err := calendar.NewService(ctx, option.WithHTTPClient(server.Client()), option.WithEndpoint(server.URL))
+	if err != nil {
+		t.Errorf("Error while creating the calendar service: %v", err)
+		return
+	}
+	rome, _ := time.LoadLocation("Europe/Rome")
+	tMin := time.Date(2025, time.December, 10, 0, 0, 0, 0, rome)
+	eventList, err := getEventsFromCalendar(ctx, srv, "primary", tMin, GetPureDateAfterDays(tMin, 1), "me@x.com")
+	if err != nil {
+		t.Errorf("Error while getting events: %v", err)
+		return
+	}
+	// declined and cancelled events are dropped
+	expectedDescriptions := []string{"Office", "Accepted", "Not answered", "Show as free", "Focus"}
+	if len(eventList) != len(expectedDescriptions) {
+		t.Errorf("Length mismatch about no. events: %v", len(eventList))
+		PrintEventList(eventList)
+		return
+	}
+	for eventIndex, event := range eventList {
+		if event.Description != expectedDescriptions[eventIndex] {
+			t.Errorf("Mismatching event index %v: %v", eventIndex, event.Description)
+		}
+	}
+	if eventList[0].EventType != EventTypeWorkingLocation || !eventList[0].AllDay ||
+		eventList[2].ResponseStatus != ResponseNeedsAction || eventList[3].Transparency != TransparencyTransparent {
+		t.Errorf("Wrong event attributes")
+	}
+
+	// only the accepted meeting and focus time make the user busy
+	freeSlotsCoreAlgorithm := FreeSlotsCoreAlgorithm{
+		NoDays:     1,
+		FromTime:   "09:00",
+		ToTime:     "18:00",
+		StartDate:  tMin,
+		AllDayBusy: true,
+	}
+	freeSlotsAgendas, _ := freeSlotsCoreAlgorithm.GetFreeSlots(SplitCalendarEventsByDay(eventList))
+	if len(freeSlotsAgendas) != 1 || len(freeSlotsAgendas[0].Events) != 2 ||
+		freeSlotsAgendas[0].Events[0].Duration != 240 || freeSlotsAgendas[0].Events[1].Duration != 120 {
+		t.Errorf("Wrong free slots with the default policy")
+		freeSlotsAgendas[0].Print(true, true)
+	}
+	freeSlotsCoreAlgorithm.BusyPolicy = BusyPolicy{TentativeIsBusy: true, IgnoreFocusTime: true}
+	freeSlotsAgendas, _ = freeSlotsCoreAlgorithm.GetFreeSlots(SplitCalendarEventsByDay(eventList))
+	if len(freeSlotsAgendas) != 1 || len(freeSlotsAgendas[0].Events) != 2 ||
+		freeSlotsAgendas[0].Events[0].StartTime.Format("15:04") != "10:00" || freeSlotsAgendas[0].Events[1].Duration != 360 {
+		t.Errorf("Wrong free slots with a custom policy")
+		freeSlotsAgendas[0].Print(true, true)
+	}
+}
+
+func TestGoogleEventPages(t *testing.T) {
+	// the second page is returned only with the token of the first one
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("pageToken") == "second" {
+			w.Write([]byte(`{"items": [{"summary": "Second", "start": {"dateTime": "2025-12-10T11:00:00Z"}, "end": {"dateTime": "2025-12-10T12:00:00Z"}}]}`))
+			return
+		}
+		w.Write([]byte(`{"nextPageToken": "second", "items": [{"summary": "First", "start": {"dateTime": "2025-12-10T09:00:00Z"}, "end": {"dateTime": "2025-12-10T10:00:00Z"}}]}`))
+	}))
+	defer server.Close()
+	ctx := context.Background()
+	srv, err := calendar.NewService(ctx, option.WithHTTPClient(server.Client()), option.WithEndpoint(server.URL))
+	if err != nil {
+		t.Errorf("Error while creating the calendar service: %v", err)
+		return
+	}
+	tMin := time.Date(2025, time.December, 10, 0, 0, 0, 0, time.UTC)
+	eventList, err := getEventsFromCalendar(ctx, srv, "shared", tMin, GetPureDateAfterDays(tMin, 1), "me@x.com")
+	if err != nil || len(eventList) != 2 || eventList[0].Description != "First" || eventList[1].Description != "Second" {
+		t.Errorf("Events of all the pages expected: %v %v", eventList, err)
+	}
+}
@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const calDavTimeFormat = "20060102T150405Z"
+
+// client of the HTTP requests made without a client given, with a timeout so that an unresponsive
+// server doesn't hang the program
+var defaultHttpClient = &http.Client{Timeout: time.Minute}
+
+// client of a CalDAV (RFC 4791) calendar collection, e.g. on Nextcloud, Radicale or iCloud
+// Authentication is basic when UserName is set, bearer when BearerToken is set.
+// HttpClient defaults to a client with a timeout of one minute
+type CalDavClient struct {
+	CalendarUrl string
+	UserName    string
+	Password    string
+	BearerToken string
+	HttpClient  *http.Client
+}
+
+// multistatus response of a calendar-query REPORT
+type calDavMultistatus struct {
+	Responses []calDavResponse `xml:"DAV: response"`
+}
+
+type calDavResponse struct {
+	Href      string           `xml:"DAV: href"`
+	Propstats []calDavPropstat `xml:"DAV: propstat"`
+}
+
+type calDavPropstat struct {
+	Prop   calDavProp `xml:"DAV: prop"`
+	Status string     `xml:"DAV: status"`
+}
+
+type calDavProp struct {
+	CalendarData string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+}
+
+// Get events from a CalDAV calendar and split them into daily agendas.
+// If freeBusyOnly is set, a free-busy-query is used and only busy periods are returned
+func GetEventsFromCalDav(calDavClient CalDavClient, tMin time.Time, noDays int, freeBusyOnly bool) ([]DailyAgenda, error) {
+	eventSource := CalDavEventSource{
+		Client:       calDavClient,
+		FreeBusyOnly: freeBusyOnly,
+	}
+	eventList, err := eventSource.Fetch(context.Background(), tMin, GetPureDateAfterDays(tMin, noDays))
+	if err != nil {
+		return nil, err
+	}
+	var dailyAgendas []DailyAgenda = SplitCalendarEventsByDay(eventList)
+	return dailyAgendas, nil
+}
+
+// get the VEVENTs overlapping [tMin, tMax) with a calendar-query REPORT.
+// Recurring events are returned as they are stored, see ExpandIcsEvents
+func (calDavClient CalDavClient) QueryEvents(ctx context.Context, tMin, tMax time.Time) ([]IcsEvent, error) {
+	requestBody := `<?xml version="1.0" encoding="utf-8" ?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop>
+    <D:getetag/>
+    <C:calendar-data/>
+  </D:prop>
+  <C:filter>
+    <C:comp-filter name="VCALENDAR">
+      <C:comp-filter name="VEVENT">
+        <C:time-range start="` + tMin.UTC().Format(calDavTimeFormat) + `" end="` + tMax.UTC().Format(calDavTimeFormat) + `"/>
+      </C:comp-filter>
+    </C:comp-filter>
+  </C:filter>
+</C:calendar-query>`
+	responseBody, err := calDavClient.report(ctx, requestBody, "1", http.StatusMultiStatus)
+	if err != nil {
+		return nil, err
+	}
+	multistatus := calDavMultistatus{}
+	if err := xml.Unmarshal(responseBody, &multistatus); err != nil {
+		return nil, fmt.Errorf("caldav: bad multistatus response: %w", err)
+	}
+	icsEvents := []IcsEvent{}
+	for _, response := range multistatus.Responses {
+		for _, propstat := range response.Propstats {
+			if propstat.Prop.CalendarData == "" || !strings.Contains(propstat.Status, " 200 ") {
+				continue
+			}
+			resourceEvents, err := ParseIcsInLocation(strings.NewReader(propstat.Prop.CalendarData), tMin.Location())
+			if err != nil {
+				return nil, fmt.Errorf("caldav: resource %s: %w", response.Href, err)
+			}
+			icsEvents = append(icsEvents, resourceEvents...)
+		}
+	}
+	return icsEvents, nil
+}
+
+// get the busy periods in [tMin, tMax) with a free-busy-query REPORT
+func (calDavClient CalDavClient) QueryFreeBusy(ctx context.Context, tMin, tMax time.Time) ([]IcsEvent, error) {
+	requestBody := `<?xml version="1.0" encoding="utf-8" ?>
+<C:free-busy-query xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <C:time-range start="` + tMin.UTC().Format(calDavTimeFormat) + `" end="` + tMax.UTC().Format(calDavTimeFormat) + `"/>
+</C:free-busy-query>`
+	// RFC 4791, section 7.10: free-busy-query has no Depth header
+	responseBody, err := calDavClient.report(ctx, requestBody, "", http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	icsEvents, err := ParseIcsFreeBusy(bytes.NewReader(responseBody))
+	if err != nil {
+		return nil, fmt.Errorf("caldav: bad free-busy response: %w", err)
+	}
+	return icsEvents, nil
+}
+
+// send a REPORT request to the calendar collection and return the body of the response.
+// The Depth header is sent only when depth is not empty
+func (calDavClient CalDavClient) report(ctx context.Context, requestBody string, depth string, expectedStatusCode int) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, "REPORT", calDavClient.CalendarUrl, strings.NewReader(requestBody))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	if depth != "" {
+		request.Header.Set("Depth", depth)
+	}
+	switch {
+	case calDavClient.BearerToken != "":
+		request.Header.Set("Authorization", "Bearer "+calDavClient.BearerToken)
+	case calDavClient.UserName != "":
+		request.SetBasicAuth(calDavClient.UserName, calDavClient.Password)
+	}
+	httpClient := calDavClient.HttpClient
+	if httpClient == nil {
+		httpClient = defaultHttpClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != expectedStatusCode {
+		return nil, fmt.Errorf("caldav: REPORT %s: unexpected status %s", calDavClient.CalendarUrl, response.Status)
+	}
+	return responseBody, nil
+}
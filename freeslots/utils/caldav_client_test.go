@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCalDavMultistatus = `<?xml version="1.0" encoding="utf-8" ?>
+<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:response>
+    <D:href>/calendars/user/work/standup.ics</D:href>
+    <D:propstat>
+      <D:prop>
+        <D:getetag>"1"</D:getetag>
+        <C:calendar-data>BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:standup
+DTSTART;TZID=Europe/Rome:20251201T093000
+DTEND;TZID=Europe/Rome:20251201T100000
+RRULE:FREQ=DAILY
+SUMMARY:Standup
+END:VEVENT
+END:VCALENDAR
+</C:calendar-data>
+      </D:prop>
+      <D:status>HTTP/1.1 200 OK</D:status>
+    </D:propstat>
+  </D:response>
+  <D:response>
+    <D:href>/calendars/user/work/review.ics</D:href>
+    <D:propstat>
+      <D:prop>
+        <D:getetag>"2"</D:getetag>
+        <C:calendar-data>BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:review
+DTSTART:20251210T130000Z
+DTEND:20251210T150000Z
+SUMMARY:Review
+END:VEVENT
+END:VCALENDAR
+</C:calendar-data>
+      </D:prop>
+      <D:status>HTTP/1.1 200 OK</D:status>
+    </D:propstat>
+  </D:response>
+</D:multistatus>`
+
+const testCalDavFreeBusy = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VFREEBUSY\r\n" +
+	"DTSTART:20251210T000000Z\r\n" +
+	"DTEND:20251211T000000Z\r\n" +
+	"FREEBUSY;FBTYPE=BUSY:20251210T083000Z/20251210T090000Z,20251210T130000Z/PT2H\r\n" +
+	"FREEBUSY;FBTYPE=FREE:20251210T150000Z/20251210T160000Z\r\n" +
+	"END:VFREEBUSY\r\n" +
+	"END:VCALENDAR\r\n"
+
+// in-process stand-in of a CalDAV server, accepting only the given bearer token
+func newTestCalDavServer(t *testing.T, bearerToken string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "REPORT" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+bearerToken {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		requestBody, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(requestBody), `start="20251209T230000Z" end="20251210T230000Z"`) {
+			t.Errorf("Unexpected time range in request: %s", requestBody)
+		}
+		_, hasDepth := r.Header["Depth"]
+		switch {
+		case strings.Contains(string(requestBody), "calendar-query") && r.Header.Get("Depth") == "1":
+			w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+			w.WriteHeader(http.StatusMultiStatus)
+			io.WriteString(w, testCalDavMultistatus)
+		case strings.Contains(string(requestBody), "free-busy-query") && !hasDepth:
+			w.Header().Set("Content-Type", "text/calendar")
+			io.WriteString(w, testCalDavFreeBusy)
+		default:
+			http.Error(w, "unsupported report", http.StatusForbidden)
+		}
+	}))
+}
+
+func TestGetEventsFromCalDav(t *testing.T) {
+	server := newTestCalDavServer(t, "secret")
+	defer server.Close()
+	rome, _ := time.LoadLocation("Europe/Rome")
+	tMin := time.Date(2025, time.December, 10, 0, 0, 0, 0, rome)
+
+	for _, freeBusyOnly := range []bool{false, true} {
+		calDavClient := CalDavClient{CalendarUrl: server.URL, BearerToken: "secret"}
+		dailyAgendas, err := GetEventsFromCalDav(calDavClient, tMin, 1, freeBusyOnly)
+		if err != nil {
+			t.Errorf("Error while querying CalDAV server: %v", err)
+			return
+		}
+		freeSlotsCoreAlgorithm := FreeSlotsCoreAlgorithm{
+			NoDays:      1,
+			MinDuration: 60,
+			FromTime:    "09:00",
+			ToTime:      "18:00",
+			StartDate:   tMin,
+		}
+		freeSlotsAgendas, err := freeSlotsCoreAlgorithm.GetFreeSlots(dailyAgendas)
+		if err != nil {
+			t.Errorf("Error while computing free slots: %v", err)
+			return
+		}
+		// standup (or busy period) 09:30-10:00, review (or busy period) 14:00-16:00
+		expectedSlots := []string{"10:00-14:00", "16:00-18:00"}
+		if len(freeSlotsAgendas) != 1 || len(freeSlotsAgendas[0].Events) != len(expectedSlots) {
+			t.Errorf("Error while computing free slots, free-busy mode %v. Different lengths", freeBusyOnly)
+			for _, freeSlotsAgenda := range freeSlotsAgendas {
+				PrintEventList(freeSlotsAgenda.Events)
+			}
+			return
+		}
+		for slotIndex, freeSlot := range freeSlotsAgendas[0].Events {
+			slot := freeSlot.StartTime.In(rome).Format("15:04") + "-" + freeSlot.GetEndTime().In(rome).Format("15:04")
+			if slot != expectedSlots[slotIndex] {
+				t.Errorf("Error while computing free slots, free-busy mode %v: expected %v, got %v",
+					freeBusyOnly, expectedSlots[slotIndex], slot)
+			}
+		}
+	}
+
+	calDavClient := CalDavClient{CalendarUrl: server.URL, BearerToken: "wrong"}
+	if _, err := GetEventsFromCalDav(calDavClient, tMin, 1, false); err == nil {
+		t.Errorf("Error expected with a wrong token")
+	}
+}
+
+func TestCalDavTimeout(t *testing.T) {
+	serverDone := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-serverDone
+	}))
+	defer server.Close()
+	defer close(serverDone)
+	previousHttpClient := defaultHttpClient
+	defer func() { defaultHttpClient = previousHttpClient }()
+	defaultHttpClient = &http.Client{Timeout: 100 * time.Millisecond}
+
+	// an unresponsive server doesn't hang the program
+	tMin := time.Date(2025, time.December, 10, 0, 0, 0, 0, time.UTC)
+	if _, err := GetEventsFromCalDav(CalDavClient{CalendarUrl: server.URL}, tMin, 1, false); err == nil {
+		t.Errorf("Error expected from an unresponsive server")
+	}
+}
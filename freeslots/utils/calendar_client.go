@@ -2,11 +2,18 @@ package utils
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -19,11 +26,30 @@ type CalendarExporterStatus struct {
 	CredentialsFileName     string
 	TokenFileName           string
 	WebserverAddressAndPort string
+	AuthMode                string
+	// a service account key replaces the interactive sign-in, acting as Subject if set
+	ServiceAccountFileName string
+	Subject                string
 }
 
 func CreateCalendarService(calendarExporterStatus CalendarExporterStatus) (*calendar.Service, error) {
 	ctx := context.Background()
 
+	if calendarExporterStatus.ServiceAccountFileName != "" {
+		serviceAccountKey, err := os.ReadFile(calendarExporterStatus.ServiceAccountFileName)
+		if err != nil {
+			return nil, err
+		}
+		client, err := getServiceAccountClient(ctx, serviceAccountKey, calendarExporterStatus.Subject)
+		if err != nil {
+			return nil, err
+		}
+		return calendar.NewService(ctx, option.WithHTTPClient(client))
+	}
+	if calendarExporterStatus.Subject != "" {
+		return nil, fmt.Errorf("a subject to impersonate requires a service account")
+	}
+
 	// Read credentials from JSON file
 	credentialsFile, err := os.ReadFile(calendarExporterStatus.CredentialsFileName)
 	if err != nil {
@@ -35,9 +61,8 @@ func CreateCalendarService(calendarExporterStatus CalendarExporterStatus) (*cale
 	if err != nil {
 		return nil, err
 	}
-	oauthConfiguration.RedirectURL = "http://" + calendarExporterStatus.WebserverAddressAndPort
 
-	client := getOAuthClient(oauthConfiguration, calendarExporterStatus)
+	client, err := getOAuthClient(oauthConfiguration, calendarExporterStatus)
 	if err != nil {
 		return nil, err
 	}
@@ -48,71 +73,205 @@ func CreateCalendarService(calendarExporterStatus CalendarExporterStatus) (*cale
 	return calendarService, err
 }
 
-// getOAuthClient retrieves a token, saves it, then returns the configured client
-func getOAuthClient(config *oauth2.Config, calendarExporterStatus CalendarExporterStatus) *http.Client {
+// getServiceAccountClient returns a client authenticated with a service account key. With a subject,
+// the service account impersonates that user through domain-wide delegation
+func getServiceAccountClient(ctx context.Context, serviceAccountKey []byte, subject string) (*http.Client, error) {
+	jwtConfiguration, err := google.JWTConfigFromJSON(serviceAccountKey, calendar.CalendarReadonlyScope)
+	if err != nil {
+		return nil, fmt.Errorf("bad service account key: %w", err)
+	}
+	jwtConfiguration.Subject = subject
+	return jwtConfiguration.Client(ctx), nil
+}
+
+// getOAuthClient retrieves a token, from the file or the web when missing or revoked, then returns a client
+// saving every refreshed token to the file
+func getOAuthClient(config *oauth2.Config, calendarExporterStatus CalendarExporterStatus) (*http.Client, error) {
+	ctx := context.Background()
 	tok, err := tokenFromFile(calendarExporterStatus.TokenFileName)
+	if err == nil {
+		tokenSource := newSavingTokenSource(config.TokenSource(ctx, tok), calendarExporterStatus.TokenFileName, tok)
+		// refresh an expired token now, so that a revoked one can be replaced before any API call
+		_, err = tokenSource.Token()
+		if err == nil {
+			return oauth2.NewClient(ctx, tokenSource), nil
+		}
+		if !IsInvalidGrant(err) {
+			return nil, err
+		}
+		fmt.Fprintf(authOutput, "The saved authorization has expired or was revoked, authenticating again...\n")
+	}
+	tok, err = getToken(config, calendarExporterStatus)
 	if err != nil {
-		tok = getTokenFromWeb(config, calendarExporterStatus.WebserverAddressAndPort)
-		saveToken(calendarExporterStatus.TokenFileName, tok)
+		return nil, err
 	}
-	return config.Client(context.Background(), tok)
+	fmt.Fprintf(authOutput, "Saving credential file to: %s\n", calendarExporterStatus.TokenFileName)
+	if err := saveToken(calendarExporterStatus.TokenFileName, tok); err != nil {
+		return nil, fmt.Errorf("unable to cache token: %w", err)
+	}
+	return oauth2.NewClient(ctx, newSavingTokenSource(config.TokenSource(ctx, tok), calendarExporterStatus.TokenFileName, tok)), nil
 }
 
-// getTokenFromWeb requests a token using a local web server
-func getTokenFromWeb(config *oauth2.Config, webserverAddressAndPort string) *oauth2.Token {
-	codeCh := make(chan string)
-	errCh := make(chan error)
+// getToken asks the user to sign in with the flow of the AuthMode, the local web server by default
+func getToken(config *oauth2.Config, calendarExporterStatus CalendarExporterStatus) (*oauth2.Token, error) {
+	switch calendarExporterStatus.AuthMode {
+	case "", AuthModeLocal:
+		return getTokenFromWeb(config, calendarExporterStatus.WebserverAddressAndPort)
+	case AuthModePaste:
+		return getTokenFromPastedCode(config, calendarExporterStatus.WebserverAddressAndPort)
+	}
+	return nil, fmt.Errorf("unknown authentication mode %q, use one of %v", calendarExporterStatus.AuthMode, strings.Join(AuthModes, ", "))
+}
 
-	// Start local server to receive OAuth callback
-	server := &http.Server{Addr: webserverAddressAndPort}
+// tell if the authorization server refused a refresh token because it expired or was revoked
+func IsInvalidGrant(err error) bool {
+	var retrieveError *oauth2.RetrieveError
+	return errors.As(err, &retrieveError) && retrieveError.ErrorCode == "invalid_grant"
+}
+
+// savingTokenSource saves to a file every token different from the last one, i.e. each refreshed token
+type savingTokenSource struct {
+	tokenSource   oauth2.TokenSource
+	tokenFileName string
+	mutex         sync.Mutex
+	lastToken     *oauth2.Token
+}
+
+func newSavingTokenSource(tokenSource oauth2.TokenSource, tokenFileName string, token *oauth2.Token) *savingTokenSource {
+	return &savingTokenSource{
+		tokenSource:   oauth2.ReuseTokenSource(token, tokenSource),
+		tokenFileName: tokenFileName,
+		lastToken:     token,
+	}
+}
+
+func (savingTokenSource *savingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := savingTokenSource.tokenSource.Token()
+	if err != nil {
+		if IsInvalidGrant(err) {
+			return nil, fmt.Errorf("the authorization has expired or was revoked, delete %v and run again to log in: %w", savingTokenSource.tokenFileName, err)
+		}
+		return nil, err
+	}
+	savingTokenSource.mutex.Lock()
+	defer savingTokenSource.mutex.Unlock()
+	if savingTokenSource.lastToken == nil || tok.AccessToken != savingTokenSource.lastToken.AccessToken ||
+		tok.RefreshToken != savingTokenSource.lastToken.RefreshToken {
+		if err := saveToken(savingTokenSource.tokenFileName, tok); err != nil {
+			return nil, fmt.Errorf("unable to cache refreshed token: %w", err)
+		}
+		savingTokenSource.lastToken = tok
+	}
+	return tok, nil
+}
+
+// how long getTokenFromWeb waits for the user to sign in
+var authenticationTimeout = 5 * time.Minute
+
+// openBrowser opens a URL in the default browser, when a way to do it is available
+var openBrowser = func(url string) error {
+	var command string
+	switch runtime.GOOS {
+	case "darwin":
+		command = "open"
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	default:
+		command = "xdg-open"
+	}
+	if _, err := exec.LookPath(command); err != nil {
+		return err
+	}
+	return exec.Command(command, url).Start()
+}
+
+// result of the OAuth callback: the authorization code or the error returned by the server
+type authCallbackResult struct {
+	code string
+	err  error
+}
 
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		code := r.URL.Query().Get("code")
+// getTokenFromWeb requests a token using a local web server, with a random state and PKCE
+func getTokenFromWeb(config *oauth2.Config, webserverAddressAndPort string) (*oauth2.Token, error) {
+	listener, err := net.Listen("tcp", webserverAddressAndPort)
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen for the authentication callback: %w", err)
+	}
+	// the actual port is known only now when 0 is requested
+	host, _, _ := net.SplitHostPort(webserverAddressAndPort)
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	webConfig := *config
+	webConfig.RedirectURL = "http://" + net.JoinHostPort(host, port)
+
+	state := rand.Text()
+	verifier := oauth2.GenerateVerifier()
+	resultCh := make(chan authCallbackResult, 1)
+	sendResult := func(result authCallbackResult) {
+		select {
+		case resultCh <- result:
+		default:
+			// a result was already received
+		}
+	}
+
+	// Start local server to receive OAuth callback
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("state") != state {
+			http.Error(w, "Invalid state", http.StatusBadRequest)
+			return
+		}
+		if authError := query.Get("error"); authError != "" {
+			http.Error(w, "Authentication failed: "+authError, http.StatusUnauthorized)
+			sendResult(authCallbackResult{err: fmt.Errorf("authentication denied: %v %v", authError, query.Get("error_description"))})
+			return
+		}
+		code := query.Get("code")
 		if code == "" {
-			errCh <- fmt.Errorf("no code in response")
+			http.Error(w, "No code in response", http.StatusBadRequest)
 			return
 		}
-
 		w.Header().Set("Content-Type", "text/html")
 		fmt.Fprintf(w, "<html><body><h1>Authentication successful!</h1><p>You can close this window and return to the terminal.</p></body></html>")
-		codeCh <- code
+		sendResult(authCallbackResult{code: code})
 	})
-
+	server := &http.Server{Handler: mux}
 	go func() {
-		if err := server.ListenAndServe(); err != http.ErrServerClosed {
-			errCh <- err
+		if err := server.Serve(listener); err != http.ErrServerClosed {
+			sendResult(authCallbackResult{err: err})
 		}
 	}()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
 
-	// Generate auth URL with localhost redirect
-	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
-	fmt.Printf("Opening browser for authentication...\n")
-	fmt.Printf("If the browser doesn't open, go to:\n%v\n\n", authURL)
-
-	// Try to open browser (this may not work on all systems)
-	fmt.Println("Waiting for authentication...")
+	authURL := webConfig.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier))
+	fmt.Fprintf(authOutput, "Opening browser for authentication...\n")
+	fmt.Fprintf(authOutput, "If the browser doesn't open, go to:\n%v\n\n", authURL)
+	if err := openBrowser(authURL); err != nil {
+		fmt.Fprintf(authOutput, "Unable to open the browser: %v\n", err)
+	}
+	fmt.Fprintln(authOutput, "Waiting for authentication...")
 
 	// Wait for code or error
-	var authCode string
+	var result authCallbackResult
 	select {
-	case authCode = <-codeCh:
-		// Success
-	case err := <-errCh:
-		log.Fatalf("Error during authentication: %v", err)
-	case <-time.After(5 * time.Minute):
-		log.Fatal("Authentication timeout")
+	case result = <-resultCh:
+	case <-time.After(authenticationTimeout):
+		return nil, fmt.Errorf("authentication timeout")
+	}
+	if result.err != nil {
+		return nil, fmt.Errorf("error during authentication: %w", result.err)
 	}
 
-	// Shutdown server
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	server.Shutdown(ctx)
-
-	tok, err := config.Exchange(context.Background(), authCode)
+	tok, err := webConfig.Exchange(context.Background(), result.code, oauth2.VerifierOption(verifier))
 	if err != nil {
-		log.Fatalf("Unable to retrieve token: %v", err)
+		return nil, fmt.Errorf("unable to retrieve token: %w", err)
 	}
-	return tok
+	return tok, nil
 }
 
 // tokenFromFile retrieves a token from a local file
@@ -127,13 +286,27 @@ func tokenFromFile(file string) (*oauth2.Token, error) {
 	return tok, err
 }
 
-// saveToken saves a token to a file path
-func saveToken(path string, token *oauth2.Token) {
-	fmt.Printf("Saving credential file to: %s\n", path)
-	f, err := os.Create(path)
+// saveToken saves a token to a file path, replacing it atomically so that a crash never leaves it truncated
+func saveToken(path string, token *oauth2.Token) error {
+	// e.g. the directory of a new profile
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tempFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
 	if err != nil {
-		log.Fatalf("Unable to cache token: %v", err)
+		return err
 	}
-	defer f.Close()
-	json.NewEncoder(f).Encode(token)
+	defer os.Remove(tempFile.Name())
+	if err := json.NewEncoder(tempFile).Encode(token); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Sync(); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tempFile.Name(), path)
 }
@@ -0,0 +1,229 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestSavingTokenSource(t *testing.T) {
+	refreshTokenRevoked := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if refreshTokenRevoked {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": "invalid_grant", "error_description": "Token has been expired or revoked."}`)
+			return
+		}
+		fmt.Fprint(w, `{"access_token": "new-access", "refresh_token": "new-refresh", "token_type": "Bearer", "expires_in": 3600}`)
+	}))
+	defer server.Close()
+	config := &oauth2.Config{
+		ClientID: "client",
+		Endpoint: oauth2.Endpoint{TokenURL: server.URL, AuthStyle: oauth2.AuthStyleInParams},
+	}
+	tokenFileName := filepath.Join(t.TempDir(), "token.json")
+	expiredToken := &oauth2.Token{AccessToken: "old-access", RefreshToken: "old-refresh", Expiry: time.Now().Add(-time.Hour)}
+	if err := saveToken(tokenFileName, expiredToken); err != nil {
+		t.Errorf("Error while saving the token: %v", err)
+		return
+	}
+
+	// the refreshed token replaces the expired one in the file
+	ctx := context.Background()
+	tokenSource := newSavingTokenSource(config.TokenSource(ctx, expiredToken), tokenFileName, expiredToken)
+	tok, err := tokenSource.Token()
+	if err != nil || tok.AccessToken != "new-access" {
+		t.Errorf("Token not refreshed: %v %v", tok, err)
+		return
+	}
+	savedToken, err := tokenFromFile(tokenFileName)
+	if err != nil || savedToken.AccessToken != "new-access" || savedToken.RefreshToken != "new-refresh" {
+		t.Errorf("Refreshed token not saved: %v %v", savedToken, err)
+	}
+	if tempFiles, _ := filepath.Glob(tokenFileName + ".*"); len(tempFiles) > 0 {
+		t.Errorf("Temporary files left: %v", tempFiles)
+	}
+
+	// a revoked refresh token is reported as such
+	refreshTokenRevoked = true
+	tokenSource = newSavingTokenSource(config.TokenSource(ctx, expiredToken), tokenFileName, expiredToken)
+	if _, err := tokenSource.Token(); !IsInvalidGrant(err) {
+		t.Errorf("Invalid grant not detected: %v", err)
+	}
+	if savedToken, _ := tokenFromFile(tokenFileName); savedToken.AccessToken != "new-access" {
+		t.Errorf("Token file changed after a failed refresh")
+	}
+}
+
+func TestTokenFromFileErrors(t *testing.T) {
+	if _, err := tokenFromFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("No error for a missing token file")
+	}
+	brokenFileName := filepath.Join(t.TempDir(), "broken.json")
+	os.WriteFile(brokenFileName, []byte("{"), 0600)
+	if _, err := tokenFromFile(brokenFileName); err == nil {
+		t.Errorf("No error for a broken token file")
+	}
+}
+
+// fakeOAuthServer issues the code "auth-code" and exchanges it for a token only with the right PKCE verifier
+func fakeOAuthServer(t *testing.T) (*httptest.Server, *oauth2.Config, *string) {
+	codeChallenge := new(string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		verifierHash := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if r.Form.Get("code") != "auth-code" || base64.RawURLEncoding.EncodeToString(verifierHash[:]) != *codeChallenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": "invalid_grant"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "web-access", "refresh_token": "web-refresh", "token_type": "Bearer", "expires_in": 3600}`)
+	}))
+	t.Cleanup(server.Close)
+	config := &oauth2.Config{
+		ClientID: "client",
+		Endpoint: oauth2.Endpoint{AuthURL: server.URL + "/auth", TokenURL: server.URL + "/token", AuthStyle: oauth2.AuthStyleInParams},
+	}
+	return server, config, codeChallenge
+}
+
+func TestGetTokenFromWeb(t *testing.T) {
+	_, config, codeChallenge := fakeOAuthServer(t)
+	defaultOpenBrowser, defaultAuthOutput := openBrowser, authOutput
+	defer func() { openBrowser, authOutput = defaultOpenBrowser, defaultAuthOutput }()
+	authOutput = io.Discard
+
+	testCases := []struct {
+		// query parameters sent to the callback in order, the state is added when missing
+		callbacks   []url.Values
+		statusCodes []int
+		accessToken string
+	}{
+		{[]url.Values{{"code": {"auth-code"}}}, []int{http.StatusOK}, "web-access"},
+		// stray requests don't stop the flow
+		{[]url.Values{{"state": {"forged"}, "code": {"auth-code"}}, {}, {"code": {"auth-code"}}},
+			[]int{http.StatusBadRequest, http.StatusBadRequest, http.StatusOK}, "web-access"},
+		{[]url.Values{{"error": {"access_denied"}}}, []int{http.StatusUnauthorized}, ""},
+		// a code exchanged with the wrong verifier is refused
+		{[]url.Values{{"code": {"other-code"}}}, []int{http.StatusOK}, ""},
+	}
+	for testIndex, testCase := range testCases {
+		var statusCodes []int
+		callbacksDone := make(chan struct{})
+		openBrowser = func(authURL string) error {
+			parsedURL, _ := url.Parse(authURL)
+			query := parsedURL.Query()
+			if query.Get("code_challenge_method") != "S256" || query.Get("state") == "" {
+				t.Errorf("Test case %v: missing PKCE or state in %v", testIndex, authURL)
+			}
+			*codeChallenge = query.Get("code_challenge")
+			go func() {
+				defer close(callbacksDone)
+				for _, callback := range testCase.callbacks {
+					if !callback.Has("state") {
+						callback.Set("state", query.Get("state"))
+					}
+					response, err := http.Get(query.Get("redirect_uri") + "/?" + callback.Encode())
+					if err != nil {
+						t.Errorf("Test case %v: callback failed: %v", testIndex, err)
+						return
+					}
+					response.Body.Close()
+					statusCodes = append(statusCodes, response.StatusCode)
+				}
+			}()
+			return nil
+		}
+		tok, err := getTokenFromWeb(config, "127.0.0.1:0")
+		if testCase.accessToken == "" {
+			if err == nil {
+				t.Errorf("Test case %v: no error", testIndex)
+			}
+		} else if err != nil || tok.AccessToken != testCase.accessToken {
+			t.Errorf("Test case %v: wrong token %v %v", testIndex, tok, err)
+		}
+		// the last response can arrive after the token
+		<-callbacksDone
+		if fmt.Sprint(statusCodes) != fmt.Sprint(testCase.statusCodes) {
+			t.Errorf("Test case %v: wrong status codes %v", testIndex, statusCodes)
+		}
+	}
+}
+
+func TestGetServiceAccountClient(t *testing.T) {
+	var claims map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/token" {
+			// the payload of the signed JWT assertion tells who the service account acts as
+			r.ParseForm()
+			assertionParts := strings.Split(r.Form.Get("assertion"), ".")
+			if len(assertionParts) == 3 {
+				payload, _ := base64.RawURLEncoding.DecodeString(assertionParts[1])
+				json.Unmarshal(payload, &claims)
+			}
+			fmt.Fprint(w, `{"access_token": "service-access", "token_type": "Bearer", "expires_in": 3600}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer service-access" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+	privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	privateKeyBytes, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+	serviceAccountKey, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "bot@project.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyBytes})),
+		"token_uri":    server.URL + "/token",
+	})
+
+	for _, subject := range []string{"", "user@corp.com"} {
+		claims = nil
+		client, err := getServiceAccountClient(context.Background(), serviceAccountKey, subject)
+		if err != nil {
+			t.Errorf("Error while creating the client: %v", err)
+			return
+		}
+		response, err := client.Get(server.URL + "/calendars")
+		if err != nil || response.StatusCode != http.StatusOK {
+			t.Errorf("Request not authorized: %v %v", response, err)
+			continue
+		}
+		response.Body.Close()
+		if claims["iss"] != "bot@project.iam.gserviceaccount.com" || claims["scope"] != "https://www.googleapis.com/auth/calendar.readonly" {
+			t.Errorf("Wrong claims: %v", claims)
+		}
+		if claimedSubject, _ := claims["sub"].(string); claimedSubject != subject {
+			t.Errorf("Wrong subject: %v", claims["sub"])
+		}
+	}
+
+	if _, err := getServiceAccountClient(context.Background(), []byte(`{"type": "authorized_user"}`), ""); err == nil {
+		t.Errorf("No error for a bad service account key")
+	}
+	if _, err := CreateCalendarService(CalendarExporterStatus{Subject: "user@corp.com"}); err == nil {
+		t.Errorf("No error for a subject without service account")
+	}
+}
@@ -14,17 +14,12 @@ func (dailyAgenda DailyAgenda) Constrain(minHours, minMinutes, maxHours, maxMinu
 	minTime := GetTimeWithSpecificHoursMinutes(dailyAgenda.Date, minHours, minMinutes)
 	var maxTime time.Time
 	if maxHours == 24 {
-		maxTime = GetTimeWithSpecificHoursMinutes(dailyAgenda.Date.AddDate(0, 0, 1), 0, 0)
+		maxTime = GetPureDateAfterDays(dailyAgenda.Date, 1)
 	} else {
 		maxTime = GetTimeWithSpecificHoursMinutes(dailyAgenda.Date, maxHours, maxMinutes)
 	}
 	for _, event := range dailyAgenda.Events {
-		currentEvent := CalendarEvent{
-			StartTime:   event.StartTime,
-			Duration:    event.Duration,
-			Description: event.Description,
-			Timezone:    event.Timezone,
-		}
+		currentEvent := event
 		if currentEvent.StartTime.Compare(minTime) < 0 {
 			// trim current event
 			durationToTrim := int(minTime.Sub(currentEvent.StartTime).Minutes())
@@ -57,8 +52,7 @@ func (dailyAgenda DailyAgenda) GetFreeSlots(minDuration, fromHours, fromMinutes,
 	*/
 	gluedEvents := GlueCalendarEvents(newDailyAgenda.Events)
 
-	currentSlotStartTime := time.Date(newDailyAgenda.Date.Year(), newDailyAgenda.Date.Month(), newDailyAgenda.Date.Day(),
-		fromHours, fromMinutes, 0, 0, newDailyAgenda.Date.Location())
+	currentSlotStartTime := GetTimeWithSpecificHoursMinutes(newDailyAgenda.Date, fromHours, fromMinutes)
 
 	for _, busyEvent := range gluedEvents {
 		switch busyEvent.StartTime.Compare(currentSlotStartTime) {
@@ -69,7 +63,7 @@ func (dailyAgenda DailyAgenda) GetFreeSlots(minDuration, fromHours, fromMinutes,
 				StartTime:   currentSlotStartTime,
 				Duration:    currentSlotDuration,
 				Description: "*",
-				Timezone:    currentSlotStartTime.Location().String(), // TODO: fix timezone
+				Timezone:    currentSlotStartTime.Location().String(),
 			}
 			if newEvent.Duration >= minDuration {
 				agendaWithOnlyFreeSlots.Events = append(agendaWithOnlyFreeSlots.Events, newEvent)
@@ -83,8 +77,7 @@ func (dailyAgenda DailyAgenda) GetFreeSlots(minDuration, fromHours, fromMinutes,
 			currentSlotStartTime = busyEvent.GetEndTime()
 		}
 	}
-	endOfTodayAllowedTimeRange := time.Date(newDailyAgenda.Date.Year(), newDailyAgenda.Date.Month(), newDailyAgenda.Date.Day(),
-		toHours, toMinutes, 0, 0, newDailyAgenda.Date.Location())
+	endOfTodayAllowedTimeRange := GetTimeWithSpecificHoursMinutes(newDailyAgenda.Date, toHours, toMinutes)
 	if endOfTodayAllowedTimeRange.Compare(currentSlotStartTime) > 0 {
 		// new free slot to append to the list of events to return
 		currentSlotDuration := int(endOfTodayAllowedTimeRange.Sub(currentSlotStartTime).Minutes())
@@ -92,7 +85,7 @@ func (dailyAgenda DailyAgenda) GetFreeSlots(minDuration, fromHours, fromMinutes,
 			StartTime:   currentSlotStartTime,
 			Duration:    currentSlotDuration,
 			Description: "*",
-			Timezone:    currentSlotStartTime.Location().String(), // TODO: fix timezone
+			Timezone:    currentSlotStartTime.Location().String(),
 		}
 		if newEvent.Duration >= minDuration {
 			agendaWithOnlyFreeSlots.Events = append(agendaWithOnlyFreeSlots.Events, newEvent)
@@ -101,10 +94,121 @@ func (dailyAgenda DailyAgenda) GetFreeSlots(minDuration, fromHours, fromMinutes,
 	return agendaWithOnlyFreeSlots, nil
 }
 
-// splits calendar events into days
+// events of the day for which keep returns true
+func (dailyAgenda DailyAgenda) FilterEvents(keep func(CalendarEvent) bool) DailyAgenda {
+	filteredAgenda := DailyAgenda{
+		Date:   dailyAgenda.Date,
+		Events: []CalendarEvent{},
+	}
+	for _, event := range dailyAgenda.Events {
+		if keep(event) {
+			filteredAgenda.Events = append(filteredAgenda.Events, event)
+		}
+	}
+	return filteredAgenda
+}
+
+// inflate the busy events of the day by bufferBefore minutes before and bufferAfter minutes after them.
+// If onlyWithPlace, only events with a place or a video link are inflated
+func (dailyAgenda DailyAgenda) AddBuffers(bufferBefore, bufferAfter int, onlyWithPlace bool) DailyAgenda {
+	bufferedAgenda := DailyAgenda{
+		Date:   dailyAgenda.Date,
+		Events: make([]CalendarEvent, 0, len(dailyAgenda.Events)),
+	}
+	for _, event := range dailyAgenda.Events {
+		if !onlyWithPlace || event.HasPlaceOrVideoLink() {
+			event.StartTime = event.StartTime.Add(-time.Duration(bufferBefore) * time.Minute)
+			event.Duration += bufferBefore + bufferAfter
+		}
+		bufferedAgenda.Events = append(bufferedAgenda.Events, event)
+	}
+	SortEventListByStartTime(&bufferedAgenda.Events)
+	return bufferedAgenda
+}
+
+// round the starts of free slots up and their ends down to a grid of align minutes of the wall clock,
+// e.g. 10:47-12:13 becomes 11:00-12:00 with align 30. Slots left empty are dropped
+func (dailyAgenda DailyAgenda) AlignFreeSlots(align int) DailyAgenda {
+	alignedAgenda := DailyAgenda{
+		Date:   dailyAgenda.Date,
+		Events: []CalendarEvent{},
+	}
+	for _, freeSlot := range dailyAgenda.Events {
+		startTime := alignWallClockTime(freeSlot.StartTime, align, true)
+		endTime := alignWallClockTime(freeSlot.GetEndTime(), align, false)
+		if !endTime.After(startTime) {
+			continue
+		}
+		freeSlot.StartTime = startTime
+		freeSlot.Duration = int(endTime.Sub(startTime).Minutes())
+		alignedAgenda.Events = append(alignedAgenda.Events, freeSlot)
+	}
+	return alignedAgenda
+}
+
+// chop free slots into consecutive candidate slots of exactly slotLength minutes, dropping the remainders
+func (dailyAgenda DailyAgenda) ChopFreeSlots(slotLength int) DailyAgenda {
+	choppedAgenda := DailyAgenda{
+		Date:   dailyAgenda.Date,
+		Events: []CalendarEvent{},
+	}
+	for _, freeSlot := range dailyAgenda.Events {
+		endTime := freeSlot.GetEndTime()
+		candidateSlot := freeSlot
+		candidateSlot.Duration = slotLength
+		for !candidateSlot.GetEndTime().After(endTime) {
+			choppedAgenda.Events = append(choppedAgenda.Events, candidateSlot)
+			candidateSlot.StartTime = candidateSlot.GetEndTime()
+		}
+	}
+	return choppedAgenda
+}
+
+// round a time up or down to a multiple of align minutes since midnight of the wall clock
+func alignWallClockTime(origTime time.Time, align int, roundUp bool) time.Time {
+	minutesOfDay := origTime.Hour()*60 + origTime.Minute()
+	alignedMinutes := minutesOfDay - minutesOfDay%align
+	isAligned := alignedMinutes == minutesOfDay && origTime.Second() == 0 && origTime.Nanosecond() == 0
+	if roundUp && !isAligned {
+		alignedMinutes += align
+	}
+	return GetWallClockTime(origTime.Year(), origTime.Month(), origTime.Day(), 0, alignedMinutes, origTime.Location())
+}
+
+// free slots of the day within each of the given windows, in order
+func (dailyAgenda DailyAgenda) GetFreeSlotsInWindows(minDuration int, timeWindows []TimeWindow) (DailyAgenda, error) {
+	agendaWithOnlyFreeSlots := DailyAgenda{
+		Date:   dailyAgenda.Date,
+		Events: []CalendarEvent{},
+	}
+	for _, timeWindow := range timeWindows {
+		windowAgenda, err := dailyAgenda.GetFreeSlots(minDuration, timeWindow.FromHours, timeWindow.FromMinutes,
+			timeWindow.ToHours, timeWindow.ToMinutes)
+		if err != nil {
+			return DailyAgenda{}, err
+		}
+		agendaWithOnlyFreeSlots.Events = append(agendaWithOnlyFreeSlots.Events, windowAgenda.Events...)
+	}
+	return agendaWithOnlyFreeSlots, nil
+}
+
+// splits calendar events into days. Events crossing midnight, like multi-day and overnight ones,
+// are cut into a portion for each day they cover
 // assumption: they are sorted by StartTime
 func SplitCalendarEventsByDay(inputEvents []CalendarEvent) []DailyAgenda {
 	outputDailyAgendas := []DailyAgenda{}
+	if len(inputEvents) == 0 {
+		return outputDailyAgendas
+	}
+	splitEvents := make([]CalendarEvent, 0, len(inputEvents))
+	for _, inputEvent := range inputEvents {
+		splitEvents = append(splitEvents, SplitCalendarEventAtMidnight(inputEvent)...)
+	}
+	if len(splitEvents) > len(inputEvents) {
+		// portions on the following days can come after other events
+		SortEventListByStartTime(&splitEvents)
+		inputEvents = splitEvents
+	}
 	var currentDailyAgendaDate time.Time
 	var currentDailyAgendaEvents []CalendarEvent
 	initMode := true
@@ -116,7 +220,7 @@ func SplitCalendarEventsByDay(inputEvents []CalendarEvent) []DailyAgenda {
 			continue
 		}
 		dayOfCalendarEvent := GetPureDate(currentEvent.StartTime)
-		if CompareTimesWithoutTimeZone(currentDailyAgendaDate, dayOfCalendarEvent) == 0 {
+		if IsSameDate(currentDailyAgendaDate, dayOfCalendarEvent) {
 			// events are on the same day
 			currentDailyAgendaEvents = append(currentDailyAgendaEvents, currentEvent)
 		} else {
@@ -137,6 +241,81 @@ func SplitCalendarEventsByDay(inputEvents []CalendarEvent) []DailyAgenda {
 	return outputDailyAgendas
 }
 
+// cut an event at every midnight of the time zone of its start, the portions keep all the other fields
+func SplitCalendarEventAtMidnight(event CalendarEvent) []CalendarEvent {
+	portions := []CalendarEvent{}
+	for {
+		nextMidnight := GetPureDateAfterDays(event.StartTime, 1)
+		if !event.GetEndTime().After(nextMidnight) {
+			return append(portions, event)
+		}
+		portion := event
+		portion.Duration = int(nextMidnight.Sub(event.StartTime).Minutes())
+		portions = append(portions, portion)
+		event.StartTime = nextMidnight
+		event.Duration -= portion.Duration
+	}
+}
+
+// move events to the given location, without changing the instants they start at
+func ConvertCalendarEventsToLocation(eventList []CalendarEvent, location *time.Location) []CalendarEvent {
+	convertedEvents := make([]CalendarEvent, 0, len(eventList))
+	for _, event := range eventList {
+		event.StartTime = event.StartTime.In(location)
+		event.Timezone = location.String()
+		convertedEvents = append(convertedEvents, event)
+	}
+	return convertedEvents
+}
+
+// move the events of daily agendas to the given location and split them again into days of that location.
+// Agendas without events are dropped
+func ConvertDailyAgendasToLocation(dailyAgendas []DailyAgenda, location *time.Location) []DailyAgenda {
+	eventList := []CalendarEvent{}
+	for _, dailyAgenda := range dailyAgendas {
+		eventList = append(eventList, ConvertCalendarEventsToLocation(dailyAgenda.Events, location)...)
+	}
+	SortEventListByStartTime(&eventList)
+	return SplitCalendarEventsByDay(eventList)
+}
+
+// split free slots sorted by start time into the days of the location of tMin, cutting them at midnight.
+// Like FillInWithEmptyDays, an agenda is returned for each day, even without slots
+func SplitFreeSlotsByDay(freeSlots []CalendarEvent, tMin time.Time, noDays int, skipWeekends bool) []DailyAgenda {
+	location := tMin.Location()
+	dailyAgendas := []DailyAgenda{}
+	for dayIndex := 0; dayIndex < noDays; dayIndex++ {
+		currentDate := GetPureDateAfterDays(tMin, dayIndex)
+		if skipWeekends && (currentDate.Weekday() == time.Sunday || currentDate.Weekday() == time.Saturday) {
+			continue
+		}
+		nextDate := GetPureDateAfterDays(currentDate, 1)
+		dailyAgenda := DailyAgenda{
+			Date:   currentDate,
+			Events: []CalendarEvent{},
+		}
+		for _, freeSlot := range freeSlots {
+			startTime := freeSlot.StartTime
+			if startTime.Before(currentDate) {
+				startTime = currentDate
+			}
+			endTime := freeSlot.GetEndTime()
+			if endTime.After(nextDate) {
+				endTime = nextDate
+			}
+			if !endTime.After(startTime) {
+				continue
+			}
+			freeSlot.StartTime = startTime.In(location)
+			freeSlot.Duration = int(endTime.Sub(startTime).Minutes())
+			freeSlot.Timezone = location.String()
+			dailyAgenda.Events = append(dailyAgenda.Events, freeSlot)
+		}
+		dailyAgendas = append(dailyAgendas, dailyAgenda)
+	}
+	return dailyAgendas
+}
+
 // merge events by creating a single list of events sorted by start date
 func MergeCalendarEventLists(firstList, secondList []CalendarEvent) []CalendarEvent {
 	mergedList := make([]CalendarEvent, 0, len(firstList)+len(secondList))
@@ -177,7 +356,7 @@ func GlueCalendarEvents(eventList []CalendarEvent) []CalendarEvent {
 		case 1:
 			// the first event ends after the start of the 2nd event - let's glue them
 			// if first event ends after the 2nd, don't change the first event
-			if CompareTimesWithoutTimeZone(endTimeOfCandidateEvent, endTimeOfCurrentEvent) == -1 {
+			if endTimeOfCandidateEvent.Before(endTimeOfCurrentEvent) {
 				gapBetweenEndOfTwoEvents := endTimeOfCurrentEvent.Sub(endTimeOfCandidateEvent)
 				overlap := int(gapBetweenEndOfTwoEvents.Minutes())
 				candidateEventToGlue.Duration += currentEvent.Duration - overlap
@@ -190,28 +369,21 @@ func GlueCalendarEvents(eventList []CalendarEvent) []CalendarEvent {
 }
 
 func FillInWithEmptyDays(dailyAgendas []DailyAgenda, tMin time.Time, noDays int, skipWeekends bool) ([]DailyAgenda, error) {
-	// map agendas to dates
-	now := time.Now()
+	// map agendas to dates, days are in the time zone of tMin
 	newDailyAgendas := []DailyAgenda{}
-	mapOfDailyAgendas := make(map[time.Time]DailyAgenda)
+	mapOfDailyAgendas := make(map[string]DailyAgenda)
 	for _, dailyAgenda := range dailyAgendas {
-		// TODO: I neutralized the time zone, fix this issue
-		agendaTime := dailyAgenda.Date
-		timeWithNeutralLocation := time.Date(agendaTime.Year(), agendaTime.Month(), agendaTime.Day(),
-			0, 0, 0, 0, now.Location())
-		mapOfDailyAgendas[timeWithNeutralLocation] = dailyAgenda
+		mapOfDailyAgendas[dailyAgenda.Date.Format(time.DateOnly)] = dailyAgenda
 	}
 	for dayIndex := 0; dayIndex < noDays; dayIndex++ {
-		// TODO: I neutralized the time zone, fix this issue
-		agendaTime := tMin.AddDate(0, 0, dayIndex)
-		currentDate := time.Date(agendaTime.Year(), agendaTime.Month(), agendaTime.Day(),
-			0, 0, 0, 0, now.Location())
+		currentDate := GetPureDateAfterDays(tMin, dayIndex)
 		if skipWeekends && (currentDate.Weekday() == time.Sunday || currentDate.Weekday() == time.Saturday) {
 			continue
 		}
-		targetAgenda, agendaIndex := mapOfDailyAgendas[currentDate]
+		targetAgenda, agendaIndex := mapOfDailyAgendas[currentDate.Format(time.DateOnly)]
 		if agendaIndex {
-			// agenda found
+			// agenda found, its window is computed in the time zone of tMin
+			targetAgenda.Date = currentDate
 			newDailyAgendas = append(newDailyAgendas, targetAgenda)
 		} else {
 			// need to create a new agenda
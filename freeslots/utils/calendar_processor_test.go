@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -314,3 +315,240 @@ func TestFillInWithEmptyDays(t *testing.T) {
 		return
 	}
 }
+
+func TestMergeCalendarEventLists(t *testing.T) {
+	timeNow := time.Now()
+	firstList := []CalendarEvent{
+		CreateDefaultCalendarEventFromString(timeNow, "08:00", 60, "X"),
+		CreateDefaultCalendarEventFromString(timeNow, "12:00", 30, "X"),
+	}
+	secondList := []CalendarEvent{
+		CreateDefaultCalendarEventFromString(timeNow, "10:00", 30, "Y"),
+	}
+	for index := range firstList {
+		firstList[index].CalendarId = "primary"
+	}
+	secondList[0].CalendarId = "team"
+	mergedList := MergeCalendarEventLists(firstList, secondList)
+	expectedDescriptions := []string{"X", "Y", "X"}
+	expectedCalendarIds := []string{"primary", "team", "primary"}
+	if len(mergedList) != len(expectedDescriptions) {
+		t.Errorf("Length mismatch about no. events: %v", len(mergedList))
+		return
+	}
+	for eventIndex, event := range mergedList {
+		if event.Description != expectedDescriptions[eventIndex] || event.CalendarId != expectedCalendarIds[eventIndex] {
+			t.Errorf("Error while merging: mismatching event index %v", eventIndex)
+			PrintEventList(mergedList)
+			return
+		}
+	}
+}
+
+func TestConvertDailyAgendasToLocation(t *testing.T) {
+	location, _ := time.LoadLocation("Europe/Rome")
+	dailyAgenda, _ := ParseDailyAgendaInLocation("d2025-12-10,m30,s44,aX-Y", time.UTC)
+	// 22:00 UTC and 23:00 UTC are on different days in Rome
+	convertedAgendas := ConvertDailyAgendasToLocation([]DailyAgenda{dailyAgenda}, location)
+	expectedDates := []string{"2025-12-10", "2025-12-11"}
+	expectedStartTimes := []string{"23:00", "00:00"}
+	if len(convertedAgendas) != len(expectedDates) {
+		t.Errorf("Length mismatch about no. agendas: %v", len(convertedAgendas))
+		return
+	}
+	for agendaIndex, convertedAgenda := range convertedAgendas {
+		event := convertedAgenda.Events[0]
+		if convertedAgenda.Date.Format(time.DateOnly) != expectedDates[agendaIndex] ||
+			event.StartTime.Format("15:04") != expectedStartTimes[agendaIndex] ||
+			event.StartTime.Location() != location || event.Timezone != "Europe/Rome" {
+			t.Errorf("Error while converting agenda %v", agendaIndex)
+			convertedAgenda.Print(true, true)
+		}
+	}
+
+	// days are filled in and constrained in the time zone of tMin
+	filledInAgendas, _ := FillInWithEmptyDays(convertedAgendas, time.Date(2025, 12, 10, 0, 0, 0, 0, location), 3, false)
+	freeSlotsAgenda, _ := filledInAgendas[1].GetFreeSlots(0, 0, 0, 9, 0)
+	if len(filledInAgendas) != 3 || filledInAgendas[2].Date.Location() != location ||
+		len(freeSlotsAgenda.Events) != 1 || freeSlotsAgenda.Events[0].StartTime.Format("15:04 MST") != "00:30 CET" {
+		t.Errorf("Error while filling in agendas in a time zone")
+		freeSlotsAgenda.Print(true, true)
+	}
+}
+
+func TestAddBuffers(t *testing.T) {
+	dailyAgenda, _ := ParseDailyAgendaInLocation("d2025-12-10,m30,s18,aX--YY", time.UTC)
+	dailyAgenda.Events[1].Place = "Room 2"
+	bufferedAgenda := dailyAgenda.AddBuffers(15, 30, false)
+	expectedSlots := []string{"08:45-10:00", "10:15-12:00"}
+	for eventIndex, event := range bufferedAgenda.Events {
+		slot := event.StartTime.Format("15:04") + "-" + event.GetEndTime().Format("15:04")
+		if slot != expectedSlots[eventIndex] || event.Description != dailyAgenda.Events[eventIndex].Description {
+			t.Errorf("Error while buffering: mismatching event index %v: %v", eventIndex, slot)
+		}
+	}
+	// only events with a place or a video link are buffered
+	bufferedAgenda = dailyAgenda.AddBuffers(15, 30, true)
+	if bufferedAgenda.Events[0].Duration != 30 || bufferedAgenda.Events[1].Duration != 105 {
+		t.Errorf("Error while buffering events with a place")
+		bufferedAgenda.Print(true, true)
+	}
+	if dailyAgenda.Events[0].Duration != 30 {
+		t.Errorf("Original agenda changed by buffering")
+	}
+
+	// the free slot between the events is shrunk, min duration is checked after buffering
+	freeSlotsAgenda, _ := dailyAgenda.AddBuffers(15, 15, false).GetFreeSlots(30, 9, 0, 12, 0)
+	if len(freeSlotsAgenda.Events) != 1 || freeSlotsAgenda.Events[0].StartTime.Format("15:04") != "09:45" ||
+		freeSlotsAgenda.Events[0].Duration != 30 {
+		t.Errorf("Error while getting free slots with buffers")
+		freeSlotsAgenda.Print(true, true)
+	}
+	freeSlotsAgenda, _ = dailyAgenda.AddBuffers(15, 15, false).GetFreeSlots(45, 9, 0, 12, 0)
+	if !freeSlotsAgenda.IsEmpty() {
+		t.Errorf("Short slot not discarded after buffering")
+		freeSlotsAgenda.Print(true, true)
+	}
+}
+
+func TestAlignFreeSlots(t *testing.T) {
+	location, _ := time.LoadLocation("Asia/Kolkata")
+	day := time.Date(2025, time.December, 10, 0, 0, 0, 0, location)
+	dailyAgenda := DailyAgenda{
+		Date: day,
+		Events: []CalendarEvent{
+			CreateDefaultCalendarEvent(day, 10, 47, 86, "*"),
+			CreateDefaultCalendarEvent(day, 13, 0, 45, "a@x.com"),
+			CreateDefaultCalendarEvent(day, 15, 10, 15, "*"),
+		},
+	}
+	alignedAgenda := dailyAgenda.AlignFreeSlots(30)
+	expectedSlots := []string{"11:00-12:00", "13:00-13:30"}
+	if len(alignedAgenda.Events) != 2 {
+		t.Errorf("Length mismatch about no. aligned slots: %v", len(alignedAgenda.Events))
+		return
+	}
+	for slotIndex, freeSlot := range alignedAgenda.Events {
+		slot := freeSlot.StartTime.Format("15:04") + "-" + freeSlot.GetEndTime().Format("15:04")
+		if slot != expectedSlots[slotIndex] || freeSlot.Description != dailyAgenda.Events[slotIndex].Description {
+			t.Errorf("Error while aligning: mismatching slot index %v: %v", slotIndex, slot)
+		}
+	}
+
+	choppedAgenda := DailyAgenda{
+		Date:   day,
+		Events: []CalendarEvent{CreateDefaultCalendarEvent(day, 9, 0, 150, "*")},
+	}.ChopFreeSlots(60)
+	if len(choppedAgenda.Events) != 2 || choppedAgenda.Events[1].StartTime.Format("15:04") != "10:00" ||
+		choppedAgenda.Events[1].Duration != 60 {
+		t.Errorf("Error while chopping free slots")
+		choppedAgenda.Print(true, true)
+	}
+}
+
+func TestFreeSlotsCoreWithSlotLength(t *testing.T) {
+	// busy 09:00-10:47 and 12:13-13:05
+	dailyAgenda := DailyAgenda{
+		Date: time.Date(2025, time.December, 10, 0, 0, 0, 0, time.UTC),
+	}
+	dailyAgenda.Events = []CalendarEvent{
+		CreateDefaultCalendarEvent(dailyAgenda.Date, 9, 0, 107, "A"),
+		CreateDefaultCalendarEvent(dailyAgenda.Date, 12, 13, 52, "B"),
+	}
+	freeSlotsCoreAlgorithm := FreeSlotsCoreAlgorithm{
+		NoDays:      1,
+		MinDuration: 60,
+		FromTime:    "09:00",
+		ToTime:      "16:20",
+		StartDate:   dailyAgenda.Date,
+		Align:       30,
+	}
+	testCases := []struct {
+		slotLength    int
+		expectedSlots string
+	}{
+		{0, "11:00-12:00 13:30-16:00"},
+		{60, "11:00-12:00 13:30-14:30 14:30-15:30"},
+		{90, "13:30-15:00"},
+	}
+	for _, testCase := range testCases {
+		freeSlotsCoreAlgorithm.SlotLength = testCase.slotLength
+		freeSlotsAgendas, err := freeSlotsCoreAlgorithm.GetFreeSlots([]DailyAgenda{dailyAgenda})
+		if err != nil || len(freeSlotsAgendas) != 1 {
+			t.Errorf("Error while getting free slots: %v", err)
+			continue
+		}
+		slots := []string{}
+		for _, freeSlot := range freeSlotsAgendas[0].Events {
+			slots = append(slots, freeSlot.StartTime.Format("15:04")+"-"+freeSlot.GetEndTime().Format("15:04"))
+		}
+		if strings.Join(slots, " ") != testCase.expectedSlots {
+			t.Errorf("Slot length %v: unexpected slots %v", testCase.slotLength, slots)
+		}
+	}
+}
+
+func TestSplitMultiDayEvents(t *testing.T) {
+	rome, _ := time.LoadLocation("Europe/Rome")
+	day := time.Date(2025, time.October, 24, 0, 0, 0, 0, rome)
+	inputEvents := []CalendarEvent{
+		// Friday to Sunday, the last day lasts 25 hours
+		{StartTime: day, Duration: 3*1440 + 60, Description: "Conference", AllDay: true},
+		CreateDefaultCalendarEvent(day, 22, 0, 240, "Night flight"),
+		CreateDefaultCalendarEvent(GetPureDateAfterDays(day, 1), 9, 0, 60, "X"),
+	}
+	dailyAgendas := SplitCalendarEventsByDay(inputEvents)
+	expectedEvents := [][]string{
+		{"00:00-00:00 Conference", "22:00-00:00 Night flight"},
+		{"00:00-00:00 Conference", "00:00-02:00 Night flight", "09:00-10:00 X"},
+		{"00:00-00:00 Conference"},
+	}
+	if len(dailyAgendas) != len(expectedEvents) {
+		t.Errorf("Length mismatch about no. agendas: %v", len(dailyAgendas))
+		return
+	}
+	for dayIndex, dailyAgenda := range dailyAgendas {
+		if !dailyAgenda.Date.Equal(GetPureDateAfterDays(day, dayIndex)) || len(dailyAgenda.Events) != len(expectedEvents[dayIndex]) {
+			t.Errorf("Error while splitting day %v", dayIndex)
+			dailyAgenda.Print(true, true)
+			continue
+		}
+		for eventIndex, event := range dailyAgenda.Events {
+			eventAsString := event.StartTime.Format("15:04") + "-" + event.GetEndTime().Format("15:04") + " " + event.Description
+			if eventAsString != expectedEvents[dayIndex][eventIndex] {
+				t.Errorf("Error while splitting day %v: %q", dayIndex, eventAsString)
+			}
+		}
+	}
+	if dailyAgendas[2].Events[0].Duration != 1500 || !dailyAgendas[2].Events[0].AllDay {
+		t.Errorf("Wrong portion of the all-day event on the last day")
+	}
+}
+
+func TestFreeSlotsCoreWithAllDayEvents(t *testing.T) {
+	day := time.Date(2025, time.December, 10, 0, 0, 0, 0, time.UTC)
+	dailyAgendas := SplitCalendarEventsByDay([]CalendarEvent{
+		{StartTime: day, Duration: 2 * 1440, Description: "Vacation", AllDay: true},
+	})
+	freeSlotsCoreAlgorithm := FreeSlotsCoreAlgorithm{
+		NoDays:    3,
+		FromTime:  "09:00",
+		ToTime:    "18:00",
+		StartDate: day,
+	}
+	for _, allDayBusy := range []bool{false, true} {
+		freeSlotsCoreAlgorithm.AllDayBusy = allDayBusy
+		freeSlotsAgendas, err := freeSlotsCoreAlgorithm.GetFreeSlots(dailyAgendas)
+		if err != nil || len(freeSlotsAgendas) != 3 {
+			t.Errorf("Error while getting free slots: %v", err)
+			continue
+		}
+		for dayIndex, freeSlotsAgenda := range freeSlotsAgendas {
+			isFree := !freeSlotsAgenda.IsEmpty()
+			if isFree != (!allDayBusy || dayIndex == 2) {
+				t.Errorf("All-day busy %v: wrong free slots on day %v", allDayBusy, dayIndex)
+				freeSlotsAgenda.Print(true, true)
+			}
+		}
+	}
+}
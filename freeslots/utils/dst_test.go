@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// days around DST transitions: spring-forward days last 23 hours, fall-back days 25 hours.
+// In America/Santiago clocks are moved at midnight, so the first day of DST starts at 01:00
+var dstTestDays = []struct {
+	zone               string
+	date               string
+	startOfDay         string
+	dayMinutes         int
+	nightMinutes       int // from midnight to 06:00
+	lateEveningMinutes int // from 13:00 to the start of the next day
+}{
+	{"Europe/Rome", "2025-03-30", "00:00", 1380, 300, 660},
+	{"Europe/Rome", "2025-10-26", "00:00", 1500, 420, 660},
+	{"America/New_York", "2025-03-09", "00:00", 1380, 300, 660},
+	{"America/New_York", "2025-11-02", "00:00", 1500, 420, 660},
+	{"Australia/Sydney", "2025-04-06", "00:00", 1500, 420, 660},
+	{"Australia/Sydney", "2025-10-05", "00:00", 1380, 300, 660},
+	{"America/Santiago", "2025-04-05", "00:00", 1500, 360, 720},
+	{"America/Santiago", "2025-09-06", "00:00", 1440, 360, 660},
+	{"America/Santiago", "2025-09-07", "01:00", 1380, 300, 660},
+}
+
+func TestDstDays(t *testing.T) {
+	for _, dstTestDay := range dstTestDays {
+		location, err := time.LoadLocation(dstTestDay.zone)
+		if err != nil {
+			t.Errorf("Error while loading %v: %v", dstTestDay.zone, err)
+			continue
+		}
+		day, _ := ParseDateInLocation(time.DateOnly, dstTestDay.date, location)
+		if day.Format("2006-01-02 15:04") != dstTestDay.date+" "+dstTestDay.startOfDay {
+			t.Errorf("%v %v: wrong start of day %v", dstTestDay.zone, dstTestDay.date, day)
+			continue
+		}
+
+		dailyAgendas, _ := FillInWithEmptyDays(nil, GetPureDateAfterDays(day, -1), 3, false)
+		expectedDates := []string{
+			day.AddDate(0, 0, -1).Format(time.DateOnly),
+			dstTestDay.date,
+			time.Date(day.Year(), day.Month(), day.Day()+1, 12, 0, 0, 0, location).Format(time.DateOnly),
+		}
+		if len(dailyAgendas) != len(expectedDates) {
+			t.Errorf("%v %v: wrong number of days %v", dstTestDay.zone, dstTestDay.date, len(dailyAgendas))
+			continue
+		}
+		for dayIndex, dailyAgenda := range dailyAgendas {
+			if dailyAgenda.Date.Format(time.DateOnly) != expectedDates[dayIndex] {
+				t.Errorf("%v %v: wrong day %v", dstTestDay.zone, dstTestDay.date, dailyAgenda.Date)
+			}
+		}
+
+		// the whole day is free
+		freeSlotsAgenda, _ := dailyAgendas[1].GetFreeSlots(0, 0, 0, 24, 0)
+		if len(freeSlotsAgenda.Events) != 1 || freeSlotsAgenda.Events[0].Duration != dstTestDay.dayMinutes ||
+			!freeSlotsAgenda.Events[0].StartTime.Equal(day) ||
+			!freeSlotsAgenda.Events[0].GetEndTime().Equal(GetPureDateAfterDays(day, 1)) {
+			t.Errorf("%v %v: wrong free day", dstTestDay.zone, dstTestDay.date)
+			freeSlotsAgenda.Print(true, true)
+		}
+	}
+}
+
+func TestDstFreeSlots(t *testing.T) {
+	for _, dstTestDay := range dstTestDays {
+		location, _ := time.LoadLocation(dstTestDay.zone)
+		// busy from midnight to 06:00 and from 12:00 to 13:00, on the wall clock
+		dailyAgenda, err := ParseDailyAgendaInLocation("d"+dstTestDay.date+",m60,aXXXXXX------Y", location)
+		if err != nil {
+			t.Errorf("Error while parsing agenda: %v", err)
+			continue
+		}
+		if dailyAgenda.Events[0].Duration != dstTestDay.nightMinutes ||
+			dailyAgenda.Events[0].GetEndTime().Format("15:04") != "06:00" {
+			t.Errorf("%v %v: wrong busy night", dstTestDay.zone, dstTestDay.date)
+			dailyAgenda.Print(true, true)
+		}
+
+		freeSlotsAgenda, _ := dailyAgenda.GetFreeSlots(0, 0, 0, 24, 0)
+		expectedSlots := []string{
+			fmt.Sprintf("06:00-12:00 (%v')", 360),
+			fmt.Sprintf("13:00-%s (%v')", GetPureDateAfterDays(dailyAgenda.Date, 1).Format("15:04"), dstTestDay.lateEveningMinutes),
+		}
+		if len(freeSlotsAgenda.Events) != len(expectedSlots) {
+			t.Errorf("%v %v: wrong number of free slots", dstTestDay.zone, dstTestDay.date)
+			freeSlotsAgenda.Print(true, true)
+			continue
+		}
+		for slotIndex, freeSlot := range freeSlotsAgenda.Events {
+			slot := fmt.Sprintf("%s-%s (%v')", freeSlot.StartTime.Format("15:04"), freeSlot.GetEndTime().Format("15:04"), freeSlot.Duration)
+			if slot != expectedSlots[slotIndex] {
+				t.Errorf("%v %v: wrong free slot %v, expected %v", dstTestDay.zone, dstTestDay.date, slot, expectedSlots[slotIndex])
+			}
+		}
+
+		// working hours are not affected by night transitions
+		workingHoursAgenda, _ := dailyAgenda.GetFreeSlots(60, 9, 0, 18, 0)
+		if len(workingHoursAgenda.Events) != 2 || workingHoursAgenda.Events[0].Duration != 180 ||
+			workingHoursAgenda.Events[1].Duration != 300 {
+			t.Errorf("%v %v: wrong free slots in working hours", dstTestDay.zone, dstTestDay.date)
+			workingHoursAgenda.Print(true, true)
+		}
+	}
+}
+
+func TestDstFreeSlotsCore(t *testing.T) {
+	for _, dstTestDay := range dstTestDays {
+		location, _ := time.LoadLocation(dstTestDay.zone)
+		day, _ := ParseDateInLocation(time.DateOnly, dstTestDay.date, location)
+		var output bytes.Buffer
+		freeSlotsCoreAlgorithm := FreeSlotsCoreAlgorithm{
+			NoDays:           3,
+			FromTime:         "00:00",
+			ToTime:           "24:00",
+			Format:           "plain",
+			StartDate:        GetPureDateAfterDays(day, -1),
+			ShowSlotDuration: true,
+			Location:         location,
+			Output:           &output,
+		}
+		if err := freeSlotsCoreAlgorithm.FreeSlotsCore(nil); err != nil {
+			t.Errorf("Error while getting free slots: %v", err)
+			continue
+		}
+		lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+		expectedLine := fmt.Sprintf("%s: %s-", day.Format("2 Jan 2006"), dstTestDay.startOfDay)
+		if len(lines) != 3 || !strings.HasPrefix(lines[1], expectedLine) ||
+			!strings.HasSuffix(lines[1], fmt.Sprintf("(%v')", dstTestDay.dayMinutes)) {
+			t.Errorf("%v %v: unexpected output %q", dstTestDay.zone, dstTestDay.date, output.String())
+		}
+	}
+}
+
+func TestDstAllDayEvents(t *testing.T) {
+	for _, dstTestDay := range dstTestDays {
+		location, _ := time.LoadLocation(dstTestDay.zone)
+		day, _ := ParseDateInLocation(time.DateOnly, dstTestDay.date, location)
+		icsCalendar := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:allday\r\nDTSTART;VALUE=DATE:" + day.Format("20060102") +
+			"\r\nSUMMARY:Holiday\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+		icsEvents, err := ParseIcsInLocation(strings.NewReader(icsCalendar), location)
+		if err != nil || len(icsEvents) != 1 {
+			t.Errorf("Error while parsing all-day event: %v", err)
+			continue
+		}
+		if int(icsEvents[0].EndTime.Sub(icsEvents[0].StartTime).Minutes()) != dstTestDay.dayMinutes {
+			t.Errorf("%v %v: wrong all-day event %v-%v", dstTestDay.zone, dstTestDay.date, icsEvents[0].StartTime, icsEvents[0].EndTime)
+		}
+		// all-day events cover the whole day, whatever its length
+		calendarEvents := ConvertIcsEventsToCalendarEvents(icsEvents, day, GetPureDateAfterDays(day, 1), "test")
+		dailyAgendas := SplitCalendarEventsByDay(calendarEvents)
+		if len(dailyAgendas) != 1 || len(dailyAgendas[0].Events) != 1 || !dailyAgendas[0].Events[0].AllDay ||
+			dailyAgendas[0].Events[0].Duration != dstTestDay.dayMinutes {
+			t.Errorf("%v %v: wrong all-day event", dstTestDay.zone, dstTestDay.date)
+			PrintEventList(calendarEvents)
+		}
+	}
+}
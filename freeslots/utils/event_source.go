@@ -0,0 +1,207 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// source of calendar events, e.g. Google Calendar, iCalendar files or a CalDAV server
+// Fetch returns the events overlapping [from, to), sorted by start time
+type EventSource interface {
+	Fetch(ctx context.Context, from, to time.Time) ([]CalendarEvent, error)
+}
+
+// options used by the registered factories to create event sources.
+// Parameters holds free-form key/value pairs for sources defined outside this package
+type EventSourceOptions struct {
+	CalendarExporterStatus CalendarExporterStatus
+	UserEmail              string
+	CalendarIds            []string
+	AllCalendars           bool
+	IcsFileNames           []string
+	Agendas                []string
+	CalDavClient           CalDavClient
+	CalDavFreeBusy         bool
+	Parameters             map[string]string
+}
+
+type EventSourceFactory func(options EventSourceOptions) (EventSource, error)
+
+var (
+	eventSourceFactoriesMutex sync.RWMutex
+	eventSourceFactories      = make(map[string]EventSourceFactory)
+)
+
+func init() {
+	RegisterEventSource("google", NewGoogleEventSource)
+	RegisterEventSource("ics", NewIcsFileEventSource)
+	RegisterEventSource("caldav", NewCalDavEventSource)
+	RegisterEventSource("memory", NewMemoryEventSource)
+}
+
+// register a factory of event sources with a name, to be selected with NewEventSource.
+// Registering a name twice replaces the previous factory
+func RegisterEventSource(name string, factory EventSourceFactory) {
+	eventSourceFactoriesMutex.Lock()
+	defer eventSourceFactoriesMutex.Unlock()
+	eventSourceFactories[name] = factory
+}
+
+// create the event source registered with the given name
+func NewEventSource(name string, options EventSourceOptions) (EventSource, error) {
+	eventSourceFactoriesMutex.RLock()
+	factory, found := eventSourceFactories[name]
+	eventSourceFactoriesMutex.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("unknown event source %q, can be: %s", name, strings.Join(GetEventSourceNames(), ", "))
+	}
+	return factory(options)
+}
+
+// return the sorted names of the registered event sources
+func GetEventSourceNames() []string {
+	eventSourceFactoriesMutex.RLock()
+	defer eventSourceFactoriesMutex.RUnlock()
+	names := make([]string, 0, len(eventSourceFactories))
+	for name := range eventSourceFactories {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// events of one or more Google Calendars. Events declined by UserEmail are skipped
+type GoogleEventSource struct {
+	Service     *calendar.Service
+	CalendarIds []string
+	UserEmail   string
+}
+
+func NewGoogleEventSource(options EventSourceOptions) (EventSource, error) {
+	if options.UserEmail == "" {
+		return nil, fmt.Errorf("user email is required when reading events from Google Calendar")
+	}
+	calendarService, err := CreateCalendarService(options.CalendarExporterStatus)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create Google Calendar service: %w", err)
+	}
+	calendarIds := options.CalendarIds
+	if options.AllCalendars {
+		calendarIds, err = GetCalendarIds(calendarService)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve Google Calendar list: %w", err)
+		}
+	}
+	if len(calendarIds) == 0 {
+		calendarIds = []string{"primary"}
+	}
+	return GoogleEventSource{
+		Service:     calendarService,
+		CalendarIds: calendarIds,
+		UserEmail:   options.UserEmail,
+	}, nil
+}
+
+func (googleEventSource GoogleEventSource) Fetch(ctx context.Context, from, to time.Time) ([]CalendarEvent, error) {
+	eventList := []CalendarEvent{}
+	for _, calendarId := range googleEventSource.CalendarIds {
+		calendarEvents, err := getEventsFromCalendar(ctx, googleEventSource.Service, calendarId, from, to, googleEventSource.UserEmail)
+		if err != nil {
+			return nil, fmt.Errorf("calendar %s: %w", calendarId, err)
+		}
+		eventList = MergeCalendarEventLists(eventList, calendarEvents)
+	}
+	return eventList, nil
+}
+
+// events of local iCalendar files, with recurring events expanded
+type IcsFileEventSource struct {
+	FileNames []string
+}
+
+func NewIcsFileEventSource(options EventSourceOptions) (EventSource, error) {
+	if len(options.IcsFileNames) == 0 {
+		return nil, fmt.Errorf("no iCalendar file to read events from")
+	}
+	return IcsFileEventSource{FileNames: options.IcsFileNames}, nil
+}
+
+func (icsFileEventSource IcsFileEventSource) Fetch(ctx context.Context, from, to time.Time) ([]CalendarEvent, error) {
+	eventList := []CalendarEvent{}
+	for _, fileName := range icsFileEventSource.FileNames {
+		icsEvents, err := ParseIcsFileInLocation(fileName, from.Location())
+		if err != nil {
+			return nil, fmt.Errorf("ics file %s: %w", fileName, err)
+		}
+		expandedEvents := ExpandIcsEvents(icsEvents, from, to)
+		calendarEvents := ConvertIcsEventsToCalendarEvents(expandedEvents, from, to, fileName)
+		eventList = MergeCalendarEventLists(eventList, calendarEvents)
+	}
+	return eventList, nil
+}
+
+// events of a CalDAV calendar, with recurring events expanded.
+// If FreeBusyOnly is set, only busy periods are requested to the server
+type CalDavEventSource struct {
+	Client       CalDavClient
+	FreeBusyOnly bool
+}
+
+func NewCalDavEventSource(options EventSourceOptions) (EventSource, error) {
+	if options.CalDavClient.CalendarUrl == "" {
+		return nil, fmt.Errorf("no CalDAV calendar URL to read events from")
+	}
+	return CalDavEventSource{
+		Client:       options.CalDavClient,
+		FreeBusyOnly: options.CalDavFreeBusy,
+	}, nil
+}
+
+func (calDavEventSource CalDavEventSource) Fetch(ctx context.Context, from, to time.Time) ([]CalendarEvent, error) {
+	var icsEvents []IcsEvent
+	var err error
+	if calDavEventSource.FreeBusyOnly {
+		icsEvents, err = calDavEventSource.Client.QueryFreeBusy(ctx, from, to)
+	} else {
+		icsEvents, err = calDavEventSource.Client.QueryEvents(ctx, from, to)
+	}
+	if err != nil {
+		return nil, err
+	}
+	expandedEvents := ExpandIcsEvents(icsEvents, from, to)
+	return ConvertIcsEventsToCalendarEvents(expandedEvents, from, to, calDavEventSource.Client.CalendarUrl), nil
+}
+
+// events defined in memory with the syntax of ParseSingleDayAgenda, one string per day.
+// Times are in the time zone of the requested range
+type MemoryEventSource struct {
+	Agendas []string
+}
+
+func NewMemoryEventSource(options EventSourceOptions) (EventSource, error) {
+	return MemoryEventSource{Agendas: options.Agendas}, nil
+}
+
+func (memoryEventSource MemoryEventSource) Fetch(ctx context.Context, from, to time.Time) ([]CalendarEvent, error) {
+	eventList := []CalendarEvent{}
+	for _, agenda := range memoryEventSource.Agendas {
+		calendarEvents, err := ParseSingleDayAgendaInLocation(agenda, from.Location())
+		if err != nil {
+			return nil, fmt.Errorf("agenda %s: %w", agenda, err)
+		}
+		for _, calendarEvent := range calendarEvents {
+			if calendarEvent.GetEndTime().After(from) && calendarEvent.StartTime.Before(to) {
+				calendarEvent.CalendarId = "memory"
+				eventList = append(eventList, calendarEvent)
+			}
+		}
+	}
+	SortEventListByStartTime(&eventList)
+	return eventList, nil
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// event source defined outside the registry defaults, with a single event per day at the given hour
+type testEventSource struct {
+	hour int
+}
+
+func (eventSource testEventSource) Fetch(ctx context.Context, from, to time.Time) ([]CalendarEvent, error) {
+	eventList := []CalendarEvent{}
+	for currentDay := from; currentDay.Before(to); currentDay = currentDay.AddDate(0, 0, 1) {
+		eventList = append(eventList, CreateDefaultCalendarEvent(currentDay, eventSource.hour, 0, 60, "Test"))
+	}
+	return eventList, nil
+}
+
+// remove a registered factory, to restore the registry
+func unregisterEventSource(name string) {
+	eventSourceFactoriesMutex.Lock()
+	defer eventSourceFactoriesMutex.Unlock()
+	delete(eventSourceFactories, name)
+}
+
+func TestNewEventSource(t *testing.T) {
+	RegisterEventSource("test", func(options EventSourceOptions) (EventSource, error) {
+		return testEventSource{hour: 10}, nil
+	})
+	t.Cleanup(func() { unregisterEventSource("test") })
+	eventSource, err := NewEventSource("test", EventSourceOptions{})
+	if err != nil {
+		t.Errorf("Error while creating event source: %v", err)
+		return
+	}
+	from := time.Date(2025, time.December, 10, 0, 0, 0, 0, time.Local)
+	eventList, err := eventSource.Fetch(context.Background(), from, from.AddDate(0, 0, 3))
+	if err != nil || len(eventList) != 3 {
+		t.Errorf("Error while fetching events: %v %v", eventList, err)
+	}
+	if _, err := NewEventSource("missing", EventSourceOptions{}); err == nil {
+		t.Errorf("Error expected for an unknown event source")
+	}
+	if _, err := NewEventSource("ics", EventSourceOptions{}); err == nil {
+		t.Errorf("Error expected for an ics source without files")
+	}
+}
+
+func TestMemoryEventSource(t *testing.T) {
+	eventSource, err := NewEventSource("memory", EventSourceOptions{
+		Agendas: []string{
+			"d2025-12-11,m30,s16,aXX--YY",
+			"d2025-12-10,m30,s16,aZZ",
+			"d2025-12-20,m30,s16,aZZ",
+		},
+	})
+	if err != nil {
+		t.Errorf("Error while creating event source: %v", err)
+		return
+	}
+	from := time.Date(2025, time.December, 10, 0, 0, 0, 0, time.Local)
+	eventList, err := eventSource.Fetch(context.Background(), from, from.AddDate(0, 0, 7))
+	if err != nil {
+		t.Errorf("Error while fetching events: %v", err)
+		return
+	}
+	// events out of range are dropped, the others are sorted by start time
+	expectedDescriptions := []string{"Z", "X", "Y"}
+	if len(eventList) != len(expectedDescriptions) {
+		t.Errorf("Length mismatch about no. events: %v", len(eventList))
+		PrintEventList(eventList)
+		return
+	}
+	for eventIndex, event := range eventList {
+		if event.Description != expectedDescriptions[eventIndex] {
+			t.Errorf("Error while fetching events: mismatching event index %v", eventIndex)
+			PrintEventList(eventList)
+			return
+		}
+	}
+}
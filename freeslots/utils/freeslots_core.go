@@ -2,10 +2,13 @@ package utils
 
 import (
 	"fmt"
-	"log"
+	"io"
+	"os"
+	"slices"
 	"time"
 )
 
+// settings of the search of free slots, all the events are listed anyway
 type FreeSlotsCoreAlgorithm struct {
 	ShowAllEvents    bool
 	NoDays           int
@@ -16,81 +19,351 @@ type FreeSlotsCoreAlgorithm struct {
 	SkipWeekends     bool
 	StartDate        time.Time
 	ShowSlotDuration bool
+	Quorum           int
+	// time zone of the days, working hours and output, the one of StartDate when not set
+	Location *time.Location
+	// windows of the working hours, FromTime-ToTime every day when not set
+	Schedule *WeeklySchedule
+	// busy on every working day, besides the events of the agendas
+	Blocks []ProtectedBlock
+	// days that are not working days
+	Holidays HolidayCalendar
+	// all-day events make the user busy
+	AllDayBusy bool
+	// which events make the user busy
+	BusyPolicy BusyPolicy
+	// minutes added before and after busy events
+	BufferBefore int
+	BufferAfter  int
+	// buffers apply only to events with a place or a video link
+	BufferOnlyWithPlace bool
+	// grid in minutes the free slots are aligned to, when set
+	Align int
+	// length in minutes of the slots the free slots are chopped into, when set
+	SlotLength int
+	Output     io.Writer
 }
 
-func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) FreeSlotsCore(dailyAgendas []DailyAgenda) {
+func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) FreeSlotsCore(dailyAgendas []DailyAgenda) error {
+	dailyAgendas = ConvertDailyAgendasToLocation(dailyAgendas, freeSlotsCoreAlgorithm.GetLocation())
 	if freeSlotsCoreAlgorithm.ShowAllEvents {
-		freeSlotsCoreAlgorithm.PrintAllEvents(dailyAgendas)
-	} else {
-		freeSlotsCoreAlgorithm.PrintFreeSlots(dailyAgendas)
+		return freeSlotsCoreAlgorithm.PrintAllEvents(dailyAgendas)
 	}
+	return freeSlotsCoreAlgorithm.PrintFreeSlots(dailyAgendas)
 }
 
-func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) PrintAllEvents(dailyAgendas []DailyAgenda) {
-	switch freeSlotsCoreAlgorithm.Format {
-	case "plain":
-		for _, dailyAgenda := range dailyAgendas {
-			if freeSlotsCoreAlgorithm.SkipWeekends && dailyAgenda.IsWeekend() {
-				continue
-			}
-			dailyAgenda.Print(true, false)
+func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) PrintAllEvents(dailyAgendas []DailyAgenda) error {
+	hasSyntheticEvents := len(freeSlotsCoreAlgorithm.Blocks) > 0 || len(freeSlotsCoreAlgorithm.Holidays) > 0
+	if hasSyntheticEvents {
+		// blocks are listed among the events of the working days, holidays on their own days
+		var err error
+		dailyAgendas, err = FillInWithEmptyDays(dailyAgendas, freeSlotsCoreAlgorithm.getStartDate(),
+			freeSlotsCoreAlgorithm.NoDays, freeSlotsCoreAlgorithm.SkipWeekends)
+		if err != nil {
+			return fmt.Errorf("unable to create event lists for empty days: %w", err)
 		}
-	case "html":
-		fmt.Print("<html><style>table, th, td {  border: 1px solid black;  border-collapse: collapse;} </style> <body><table><tr><td>Date</td><td>Event</td><td>Description</td></tr>")
-		for _, dailyAgenda := range dailyAgendas {
-			if freeSlotsCoreAlgorithm.SkipWeekends && dailyAgenda.IsWeekend() {
-				continue
+		weeklySchedule := freeSlotsCoreAlgorithm.GetWeeklySchedule()
+		for dayIndex, dailyAgenda := range dailyAgendas {
+			if freeSlotsCoreAlgorithm.Holidays.IsHoliday(dailyAgenda.Date) {
+				holidayEvent := freeSlotsCoreAlgorithm.Holidays.getHolidayEvent(dailyAgenda.Date)
+				dailyAgendas[dayIndex].Events = MergeCalendarEventLists([]CalendarEvent{holidayEvent}, dailyAgenda.Events)
+			} else if weeklySchedule.IsWorkingDay(dailyAgenda.Date) {
+				dailyAgendas[dayIndex] = dailyAgenda.AddProtectedBlocks(freeSlotsCoreAlgorithm.Blocks)
 			}
-			dailyAgenda.PrintHtml(true, false)
 		}
-		fmt.Println("</table></body></html>")
-	case "markdown":
-		fmt.Println("| Date | Event | Description |")
-		fmt.Println("| -------- | -------- | -------- |")
-		for _, dailyAgenda := range dailyAgendas {
-			if freeSlotsCoreAlgorithm.SkipWeekends && dailyAgenda.IsWeekend() {
-				continue
+	}
+	agendasToPrint := []DailyAgenda{}
+	for _, dailyAgenda := range dailyAgendas {
+		if freeSlotsCoreAlgorithm.SkipWeekends && dailyAgenda.IsWeekend() {
+			continue
+		}
+		if hasSyntheticEvents && dailyAgenda.IsEmpty() {
+			continue
+		}
+		agendasToPrint = append(agendasToPrint, dailyAgenda)
+	}
+	return freeSlotsCoreAlgorithm.render(agendasToPrint, RenderOptions{
+		ShowAllEvents:    true,
+		ShowDescription:  true,
+		ShowSlotDuration: false,
+		IsBusy:           freeSlotsCoreAlgorithm.isBusy,
+	})
+}
+
+func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) FreeSlotsCoreForAttendees(attendeeAgendas []AttendeeAgenda) error {
+	location := freeSlotsCoreAlgorithm.GetLocation()
+	attendeeAgendas = slices.Clone(attendeeAgendas)
+	for attendeeIndex := range attendeeAgendas {
+		attendeeAgendas[attendeeIndex].DailyAgendas = ConvertDailyAgendasToLocation(attendeeAgendas[attendeeIndex].DailyAgendas, location)
+	}
+	if freeSlotsCoreAlgorithm.ShowAllEvents {
+		// show busy slots of all the attendees in a single list
+		eventList := []CalendarEvent{}
+		for _, attendeeAgenda := range attendeeAgendas {
+			for _, dailyAgenda := range attendeeAgenda.DailyAgendas {
+				eventList = MergeCalendarEventLists(eventList, dailyAgenda.Events)
 			}
-			dailyAgenda.PrintMarkdown(true, false)
 		}
+		return freeSlotsCoreAlgorithm.PrintAllEvents(SplitCalendarEventsByDay(eventList))
+	}
+	var freeSlotsAgendas []DailyAgenda
+	var err error
+	isQuorumMode := freeSlotsCoreAlgorithm.IsQuorumMode(attendeeAgendas)
+	if isQuorumMode {
+		// descriptions list the missing optional attendees
+		freeSlotsAgendas, err = freeSlotsCoreAlgorithm.GetQuorumFreeSlots(attendeeAgendas)
+	} else {
+		freeSlotsAgendas, err = freeSlotsCoreAlgorithm.GetCommonFreeSlots(attendeeAgendas)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get free slots: %w", err)
+	}
+	hasLocalTimes := false
+	for _, attendeeAgenda := range attendeeAgendas {
+		hasLocalTimes = hasLocalTimes || attendeeAgenda.Location != nil
 	}
+	if hasLocalTimes {
+		freeSlotsAgendas = AddLocalTimesOfAttendees(freeSlotsAgendas, attendeeAgendas)
+	}
+	return freeSlotsCoreAlgorithm.printFreeSlotsAgendas(freeSlotsAgendas, isQuorumMode || hasLocalTimes)
 }
 
-func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) PrintFreeSlots(dailyAgendas []DailyAgenda) {
-	newDailyAgendas, err := FillInWithEmptyDays(dailyAgendas, freeSlotsCoreAlgorithm.StartDate,
-		freeSlotsCoreAlgorithm.NoDays, freeSlotsCoreAlgorithm.SkipWeekends)
+// return the time zone of days and working hours
+func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) GetLocation() *time.Location {
+	switch {
+	case freeSlotsCoreAlgorithm.Location != nil:
+		return freeSlotsCoreAlgorithm.Location
+	case freeSlotsCoreAlgorithm.StartDate.IsZero():
+		return time.Local
+	}
+	return freeSlotsCoreAlgorithm.StartDate.Location()
+}
+
+// return the working windows of each day of the week
+func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) GetWeeklySchedule() WeeklySchedule {
+	if freeSlotsCoreAlgorithm.Schedule != nil {
+		return *freeSlotsCoreAlgorithm.Schedule
+	}
+	return NewWeeklySchedule(freeSlotsCoreAlgorithm.FromTime, freeSlotsCoreAlgorithm.ToTime, freeSlotsCoreAlgorithm.SkipWeekends)
+}
+
+// return midnight of the start date in the time zone of the algorithm
+func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) getStartDate() time.Time {
+	startDate := freeSlotsCoreAlgorithm.StartDate
+	return GetWallClockTime(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, freeSlotsCoreAlgorithm.GetLocation())
+}
+
+// quorum mode is used when some attendees are optional or a quorum is requested
+func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) IsQuorumMode(attendeeAgendas []AttendeeAgenda) bool {
+	if freeSlotsCoreAlgorithm.Quorum > 0 {
+		return true
+	}
+	for _, attendeeAgenda := range attendeeAgendas {
+		if attendeeAgenda.Optional {
+			return true
+		}
+	}
+	return false
+}
+
+func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) PrintFreeSlots(dailyAgendas []DailyAgenda) error {
+	freeSlotsAgendas, err := freeSlotsCoreAlgorithm.GetFreeSlots(dailyAgendas)
+	if err != nil {
+		return fmt.Errorf("unable to get free slots: %w", err)
+	}
+	return freeSlotsCoreAlgorithm.printFreeSlotsAgendas(freeSlotsAgendas, false)
+}
+
+// return the free slots of each day, from the start date for the requested number of days
+func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) GetFreeSlots(dailyAgendas []DailyAgenda) ([]DailyAgenda, error) {
+	freeSlotsAgendas, err := freeSlotsCoreAlgorithm.getFreeSlotsWithMinDuration(dailyAgendas, freeSlotsCoreAlgorithm.getMinDuration())
 	if err != nil {
-		log.Fatalf("Unable to create event lists for empty days: %v", err)
+		return nil, err
 	}
-	fromHours, fromMinutes := ParseTime(freeSlotsCoreAlgorithm.FromTime)
-	toHours, toMinutes := ParseTime(freeSlotsCoreAlgorithm.ToTime)
-	switch freeSlotsCoreAlgorithm.Format {
-	case "html":
-		fmt.Print("<html><style>table, th, td {  border: 1px solid black;  border-collapse: collapse;} </style> <body><table><tr><td>Date</td><td>Slot</td></tr>")
-	case "markdown":
-		fmt.Println("| Date | Slot |")
-		fmt.Println("| ----------- | ----------- |")
+	for dayIndex := range freeSlotsAgendas {
+		freeSlotsAgendas[dayIndex] = freeSlotsCoreAlgorithm.alignFreeSlots(freeSlotsAgendas[dayIndex])
 	}
-	for _, dailyAgenda := range newDailyAgendas {
-		freeSlotsAgenda, err := dailyAgenda.GetFreeSlots(freeSlotsCoreAlgorithm.MinDuration,
-			fromHours, fromMinutes, toHours, toMinutes)
+	return freeSlotsAgendas, nil
+}
+
+// true if the event makes the user busy
+func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) isBusy(event CalendarEvent) bool {
+	if event.AllDay && !freeSlotsCoreAlgorithm.AllDayBusy {
+		return false
+	}
+	return freeSlotsCoreAlgorithm.BusyPolicy.IsBusy(event)
+}
+
+// remove blocks and holidays from free slots computed without them
+func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) removeProtectedTime(freeSlotsAgenda DailyAgenda) DailyAgenda {
+	if freeSlotsCoreAlgorithm.Holidays.IsHoliday(freeSlotsAgenda.Date) {
+		return DailyAgenda{
+			Date:   freeSlotsAgenda.Date,
+			Events: []CalendarEvent{},
+		}
+	}
+	return freeSlotsAgenda.RemoveProtectedBlocks(freeSlotsCoreAlgorithm.Blocks)
+}
+
+// min duration of free slots, candidate slots can't be shorter than SlotLength
+func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) getMinDuration() int {
+	return max(freeSlotsCoreAlgorithm.MinDuration, freeSlotsCoreAlgorithm.SlotLength)
+}
+
+// align free slots to the grid and chop them into candidate slots. Min duration is checked after the alignment
+func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) alignFreeSlots(freeSlotsAgenda DailyAgenda) DailyAgenda {
+	if freeSlotsCoreAlgorithm.Align > 0 {
+		freeSlotsAgenda = freeSlotsAgenda.AlignFreeSlots(freeSlotsCoreAlgorithm.Align).FilterByMinDuration(freeSlotsCoreAlgorithm.getMinDuration())
+	}
+	if freeSlotsCoreAlgorithm.SlotLength > 0 {
+		freeSlotsAgenda = freeSlotsAgenda.ChopFreeSlots(freeSlotsCoreAlgorithm.SlotLength)
+	}
+	return freeSlotsAgenda
+}
+
+// return the free slots shared by all the attendees on each day
+func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) GetCommonFreeSlots(attendeeAgendas []AttendeeAgenda) ([]DailyAgenda, error) {
+	var commonFreeSlotsAgendas []DailyAgenda
+	for attendeeIndex, attendeeAgenda := range attendeeAgendas {
+		// short slots are discarded only after the intersection
+		freeSlotsAgendas, err := freeSlotsCoreAlgorithm.getFreeSlotsOfAttendee(attendeeAgenda)
 		if err != nil {
-			log.Fatalf("Unable to get free slots: %v", err)
-			return
+			return nil, fmt.Errorf("attendee %s: %w", attendeeAgenda.Email, err)
 		}
-		if freeSlotsAgenda.IsEmpty() {
+		if attendeeIndex == 0 {
+			commonFreeSlotsAgendas = freeSlotsAgendas
 			continue
 		}
-		switch freeSlotsCoreAlgorithm.Format {
-		case "html":
-			freeSlotsAgenda.PrintHtml(false, freeSlotsCoreAlgorithm.ShowSlotDuration)
-		case "plain":
-			freeSlotsAgenda.Print(false, freeSlotsCoreAlgorithm.ShowSlotDuration)
-		case "markdown":
-			freeSlotsAgenda.PrintMarkdown(false, freeSlotsCoreAlgorithm.ShowSlotDuration)
+		// agendas of all the attendees cover the same days
+		for dayIndex := range commonFreeSlotsAgendas {
+			commonFreeSlotsAgendas[dayIndex] = IntersectFreeSlots(commonFreeSlotsAgendas[dayIndex], freeSlotsAgendas[dayIndex])
+		}
+	}
+	for dayIndex := range commonFreeSlotsAgendas {
+		commonFreeSlotsAgendas[dayIndex] = freeSlotsCoreAlgorithm.removeProtectedTime(commonFreeSlotsAgendas[dayIndex]).
+			FilterByMinDuration(freeSlotsCoreAlgorithm.getMinDuration())
+		commonFreeSlotsAgendas[dayIndex] = freeSlotsCoreAlgorithm.alignFreeSlots(commonFreeSlotsAgendas[dayIndex])
+	}
+	return commonFreeSlotsAgendas, nil
+}
+
+// return the slots where all the required attendees and at least Quorum attendees are free on each day.
+// If Quorum is not set, all the attendees are needed
+func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) GetQuorumFreeSlots(attendeeAgendas []AttendeeAgenda) ([]DailyAgenda, error) {
+	quorum := freeSlotsCoreAlgorithm.Quorum
+	if quorum <= 0 {
+		quorum = len(attendeeAgendas)
+	}
+	attendeesFreeSlotsAgendas := make([][]DailyAgenda, 0, len(attendeeAgendas))
+	for _, attendeeAgenda := range attendeeAgendas {
+		freeSlotsAgendas, err := freeSlotsCoreAlgorithm.getFreeSlotsOfAttendee(attendeeAgenda)
+		if err != nil {
+			return nil, fmt.Errorf("attendee %s: %w", attendeeAgenda.Email, err)
+		}
+		attendeesFreeSlotsAgendas = append(attendeesFreeSlotsAgendas, freeSlotsAgendas)
+	}
+	quorumFreeSlotsAgendas := []DailyAgenda{}
+	if len(attendeesFreeSlotsAgendas) == 0 {
+		return quorumFreeSlotsAgendas, nil
+	}
+	// agendas of all the attendees cover the same days
+	for dayIndex := range attendeesFreeSlotsAgendas[0] {
+		dailyFreeSlotsAgendas := make([]DailyAgenda, 0, len(attendeesFreeSlotsAgendas))
+		for _, freeSlotsAgendas := range attendeesFreeSlotsAgendas {
+			dailyFreeSlotsAgendas = append(dailyFreeSlotsAgendas, freeSlotsAgendas[dayIndex])
+		}
+		quorumFreeSlotsAgenda := SweepFreeSlots(dailyFreeSlotsAgendas, attendeeAgendas, quorum)
+		quorumFreeSlotsAgenda = freeSlotsCoreAlgorithm.removeProtectedTime(quorumFreeSlotsAgenda)
+		quorumFreeSlotsAgenda = quorumFreeSlotsAgenda.FilterByMinDuration(freeSlotsCoreAlgorithm.getMinDuration())
+		quorumFreeSlotsAgendas = append(quorumFreeSlotsAgendas, freeSlotsCoreAlgorithm.alignFreeSlots(quorumFreeSlotsAgenda))
+	}
+	return quorumFreeSlotsAgendas, nil
+}
+
+// return the free slots of an attendee within their working hours, split into the days of the algorithm.
+// Short slots, blocks and holidays are kept, they are discarded only after the intersection
+func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) getFreeSlotsOfAttendee(attendeeAgenda AttendeeAgenda) ([]DailyAgenda, error) {
+	freeSlotsCoreAlgorithm.Blocks = nil
+	freeSlotsCoreAlgorithm.Holidays = nil
+	if !attendeeAgenda.HasOwnWorkingHours() {
+		return freeSlotsCoreAlgorithm.getFreeSlotsWithMinDuration(attendeeAgenda.DailyAgendas, 0)
+	}
+	attendeeAlgorithm := freeSlotsCoreAlgorithm
+	if attendeeAgenda.Location != nil {
+		attendeeAlgorithm.Location = attendeeAgenda.Location
+	}
+	if attendeeAgenda.FromTime != "" {
+		attendeeAlgorithm.FromTime = attendeeAgenda.FromTime
+		attendeeAlgorithm.ToTime = attendeeAgenda.ToTime
+		attendeeAlgorithm.Schedule = nil
+	}
+	// local days of the attendee overlapping the days of the algorithm
+	attendeeAlgorithm.StartDate = GetPureDateAfterDays(freeSlotsCoreAlgorithm.getStartDate(), -1)
+	attendeeAlgorithm.NoDays = freeSlotsCoreAlgorithm.NoDays + 2
+	localDailyAgendas := ConvertDailyAgendasToLocation(attendeeAgenda.DailyAgendas, attendeeAlgorithm.GetLocation())
+	localFreeSlotsAgendas, err := attendeeAlgorithm.getFreeSlotsWithMinDuration(localDailyAgendas, 0)
+	if err != nil {
+		return nil, err
+	}
+	freeSlots := []CalendarEvent{}
+	for _, localFreeSlotsAgenda := range localFreeSlotsAgendas {
+		freeSlots = append(freeSlots, localFreeSlotsAgenda.Events...)
+	}
+	return SplitFreeSlotsByDay(freeSlots, freeSlotsCoreAlgorithm.getStartDate(),
+		freeSlotsCoreAlgorithm.NoDays, freeSlotsCoreAlgorithm.SkipWeekends), nil
+}
+
+func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) getFreeSlotsWithMinDuration(dailyAgendas []DailyAgenda, minDuration int) ([]DailyAgenda, error) {
+	newDailyAgendas, err := FillInWithEmptyDays(dailyAgendas, freeSlotsCoreAlgorithm.getStartDate(),
+		freeSlotsCoreAlgorithm.NoDays, freeSlotsCoreAlgorithm.SkipWeekends)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create event lists for empty days: %w", err)
+	}
+	weeklySchedule := freeSlotsCoreAlgorithm.GetWeeklySchedule()
+	freeSlotsAgendas := make([]DailyAgenda, 0, len(newDailyAgendas))
+	for _, dailyAgenda := range newDailyAgendas {
+		dailyAgenda = dailyAgenda.FilterEvents(freeSlotsCoreAlgorithm.isBusy)
+		// buffers don't apply to blocks, short slots are discarded after buffering
+		dailyAgenda = dailyAgenda.AddBuffers(freeSlotsCoreAlgorithm.BufferBefore, freeSlotsCoreAlgorithm.BufferAfter,
+			freeSlotsCoreAlgorithm.BufferOnlyWithPlace)
+		dailyAgenda = dailyAgenda.AddProtectedBlocks(freeSlotsCoreAlgorithm.Blocks)
+		// holidays and days without working windows stay empty
+		var timeWindows []TimeWindow
+		if !freeSlotsCoreAlgorithm.Holidays.IsHoliday(dailyAgenda.Date) {
+			timeWindows = weeklySchedule.GetWindows(dailyAgenda.Date)
+		}
+		freeSlotsAgenda, err := dailyAgenda.GetFreeSlotsInWindows(minDuration, timeWindows)
+		if err != nil {
+			return nil, err
 		}
+		freeSlotsAgendas = append(freeSlotsAgendas, freeSlotsAgenda)
+	}
+	return freeSlotsAgendas, nil
+}
+
+func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) printFreeSlotsAgendas(freeSlotsAgendas []DailyAgenda, showDescription bool) error {
+	agendasToPrint := []DailyAgenda{}
+	for _, freeSlotsAgenda := range freeSlotsAgendas {
+		if !freeSlotsAgenda.IsEmpty() {
+			agendasToPrint = append(agendasToPrint, freeSlotsAgenda)
+		}
+	}
+	return freeSlotsCoreAlgorithm.render(agendasToPrint, RenderOptions{
+		ShowAllEvents:    false,
+		ShowDescription:  showDescription,
+		ShowSlotDuration: freeSlotsCoreAlgorithm.ShowSlotDuration,
+	})
+}
+
+// render agendas with the renderer registered for Format, writing to Output (stdout if not set)
+func (freeSlotsCoreAlgorithm FreeSlotsCoreAlgorithm) render(dailyAgendas []DailyAgenda, renderOptions RenderOptions) error {
+	renderer, err := GetRenderer(freeSlotsCoreAlgorithm.Format)
+	if err != nil {
+		return err
 	}
-	if freeSlotsCoreAlgorithm.Format == "html" {
-		fmt.Println("</table></body></html>")
+	output := freeSlotsCoreAlgorithm.Output
+	if output == nil {
+		output = os.Stdout
 	}
+	return renderer.Render(output, dailyAgendas, renderOptions)
 }
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// ways to sign in: a local web server receiving the redirect, or the redirected URL pasted by the user.
+// The device authorization grant is not available, since Google doesn't allow it for the calendar scopes
+const (
+	AuthModeLocal = "local"
+	AuthModePaste = "paste"
+)
+
+var AuthModes = []string{AuthModeLocal, AuthModePaste}
+
+// where getTokenFromPastedCode reads the pasted URL or code
+var authInput io.Reader = os.Stdin
+
+// where the sign-in flows write their messages: the standard output carries the free slots
+var authOutput io.Writer = os.Stderr
+
+// getTokenFromPastedCode requests a token without a local web server: the browser is redirected to
+// an address nobody listens to, and the user pastes that URL, or just its code, in the terminal
+func getTokenFromPastedCode(config *oauth2.Config, webserverAddressAndPort string) (*oauth2.Token, error) {
+	pasteConfig := *config
+	pasteConfig.RedirectURL = "http://" + webserverAddressAndPort
+	state := rand.Text()
+	verifier := oauth2.GenerateVerifier()
+	authURL := pasteConfig.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier))
+	fmt.Fprintf(authOutput, "Go to the following link in your browser:\n%v\n\n", authURL)
+	fmt.Fprintf(authOutput, "After signing in, the browser fails to load a page on %v: paste its whole URL, or the code in it, here:\n", webserverAddressAndPort)
+
+	line, err := bufio.NewReader(authInput).ReadString('\n')
+	if err != nil && line == "" {
+		return nil, fmt.Errorf("unable to read the authorization code: %w", err)
+	}
+	code, err := parsePastedCode(strings.TrimSpace(line), state)
+	if err != nil {
+		return nil, err
+	}
+	tok, err := pasteConfig.Exchange(context.Background(), code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve token: %w", err)
+	}
+	return tok, nil
+}
+
+// parsePastedCode gets the code from a redirected URL, checking its state, or takes the text as the code
+func parsePastedCode(pasted string, state string) (string, error) {
+	if pasted == "" {
+		return "", fmt.Errorf("no authorization code pasted")
+	}
+	if !strings.Contains(pasted, "code=") && !strings.Contains(pasted, "error=") {
+		return pasted, nil
+	}
+	var query url.Values
+	if redirectURL, err := url.Parse(pasted); err == nil && redirectURL.RawQuery != "" {
+		query = redirectURL.Query()
+	} else if query, err = url.ParseQuery(strings.TrimPrefix(pasted, "?")); err != nil {
+		return "", fmt.Errorf("unable to parse the pasted URL: %w", err)
+	}
+	if query.Get("state") != state {
+		return "", fmt.Errorf("invalid state in the pasted URL")
+	}
+	if authError := query.Get("error"); authError != "" {
+		return "", fmt.Errorf("authentication denied: %v %v", authError, query.Get("error_description"))
+	}
+	if query.Get("code") == "" {
+		return "", fmt.Errorf("no code in the pasted URL")
+	}
+	return query.Get("code"), nil
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestParsePastedCode(t *testing.T) {
+	testCases := []struct {
+		pasted string
+		code   string
+		valid  bool
+	}{
+		{"4/0Ab-code", "4/0Ab-code", true},
+		{"http://localhost:8080/?state=S&code=4/0Ab-code&scope=calendar", "4/0Ab-code", true},
+		{"localhost:8080/?state=S&code=abc", "abc", true},
+		{"state=S&code=abc", "abc", true},
+		{"http://localhost:8080/?state=forged&code=abc", "", false},
+		{"http://localhost:8080/?state=S&error=access_denied", "", false},
+		{"http://localhost:8080/?state=S&code=", "", false},
+		{"", "", false},
+	}
+	for _, testCase := range testCases {
+		code, err := parsePastedCode(testCase.pasted, "S")
+		if (err == nil) != testCase.valid || code != testCase.code {
+			t.Errorf("Wrong code from %q: %q %v", testCase.pasted, code, err)
+		}
+	}
+}
+
+func TestGetTokenFromPastedCode(t *testing.T) {
+	_, config, codeChallenge := fakeOAuthServer(t)
+	defaultAuthOutput, defaultAuthInput := authOutput, authInput
+	defer func() { authOutput, authInput = defaultAuthOutput, defaultAuthInput }()
+	outputReader, outputWriter := io.Pipe()
+	inputReader, inputWriter := io.Pipe()
+	authOutput, authInput = outputWriter, inputReader
+
+	// the user opens the printed link and pastes the URL the browser is redirected to
+	go func() {
+		scanner := bufio.NewScanner(outputReader)
+		for scanner.Scan() {
+			if !strings.HasPrefix(scanner.Text(), "http") {
+				continue
+			}
+			authURL, _ := url.Parse(scanner.Text())
+			query := authURL.Query()
+			*codeChallenge = query.Get("code_challenge")
+			go fmt.Fprintf(inputWriter, "%v/?state=%v&code=auth-code\n", query.Get("redirect_uri"), query.Get("state"))
+			break
+		}
+		io.Copy(io.Discard, outputReader)
+	}()
+	tok, err := getTokenFromPastedCode(config, "localhost:8080")
+	outputWriter.Close()
+	if err != nil || tok.AccessToken != "web-access" {
+		t.Errorf("Wrong token %v %v", tok, err)
+	}
+}
+
+func TestGetTokenWithUnknownMode(t *testing.T) {
+	config := &oauth2.Config{ClientID: "client"}
+	for _, authMode := range []string{"carrier-pigeon", "device"} {
+		if _, err := getToken(config, CalendarExporterStatus{AuthMode: authMode}); err == nil {
+			t.Errorf("No error for the authentication mode %v", authMode)
+		}
+	}
+}
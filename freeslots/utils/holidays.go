@@ -0,0 +1,304 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CalendarId of the synthetic events listing holidays among all the events
+const HolidayCalendarId = "holidays"
+
+// names of holidays by date, in the form yyyy-MM-dd. Holidays are not working days
+type HolidayCalendar map[string]string
+
+type holidayRuleKind int
+
+const (
+	fixedDateRule holidayRuleKind = iota
+	easterRule
+	nthWeekdayRule
+)
+
+// a holiday recurring every year: on a fixed date, a number of days after Easter Sunday,
+// or on the nth weekday of a month (-1 is the last one)
+type holidayRule struct {
+	name         string
+	kind         holidayRuleKind
+	month        time.Month
+	day          int
+	easterOffset int
+	weekday      time.Weekday
+	nth          int
+}
+
+type holidaySubstitution int
+
+const (
+	// holidays on weekends are not moved
+	noSubstitution holidaySubstitution = iota
+	// holidays on Saturday are observed on Friday, on Sunday on Monday
+	nearestWeekdaySubstitution
+	// holidays on weekends are observed on the next working day
+	nextWeekdaySubstitution
+)
+
+type holidayRules struct {
+	substitution holidaySubstitution
+	rules        []holidayRule
+}
+
+func fixedDate(name string, month time.Month, day int) holidayRule {
+	return holidayRule{name: name, kind: fixedDateRule, month: month, day: day}
+}
+
+func afterEaster(name string, easterOffset int) holidayRule {
+	return holidayRule{name: name, kind: easterRule, easterOffset: easterOffset}
+}
+
+func nthWeekday(name string, month time.Month, weekday time.Weekday, nth int) holidayRule {
+	return holidayRule{name: name, kind: nthWeekdayRule, month: month, weekday: weekday, nth: nth}
+}
+
+// national public holidays by country code. One-off holidays are not included
+var holidayRulesByCountry = map[string]holidayRules{
+	"DE": {noSubstitution, []holidayRule{
+		fixedDate("New Year's Day", time.January, 1),
+		afterEaster("Good Friday", -2),
+		afterEaster("Easter Monday", 1),
+		fixedDate("Labour Day", time.May, 1),
+		afterEaster("Ascension Day", 39),
+		afterEaster("Whit Monday", 50),
+		fixedDate("German Unity Day", time.October, 3),
+		fixedDate("Christmas Day", time.December, 25),
+		fixedDate("Second Day of Christmas", time.December, 26),
+	}},
+	"ES": {noSubstitution, []holidayRule{
+		fixedDate("New Year's Day", time.January, 1),
+		fixedDate("Epiphany", time.January, 6),
+		afterEaster("Good Friday", -2),
+		fixedDate("Labour Day", time.May, 1),
+		fixedDate("Assumption Day", time.August, 15),
+		fixedDate("National Day", time.October, 12),
+		fixedDate("All Saints' Day", time.November, 1),
+		fixedDate("Constitution Day", time.December, 6),
+		fixedDate("Immaculate Conception", time.December, 8),
+		fixedDate("Christmas Day", time.December, 25),
+	}},
+	"FR": {noSubstitution, []holidayRule{
+		fixedDate("New Year's Day", time.January, 1),
+		afterEaster("Easter Monday", 1),
+		fixedDate("Labour Day", time.May, 1),
+		fixedDate("Victory in Europe Day", time.May, 8),
+		afterEaster("Ascension Day", 39),
+		afterEaster("Whit Monday", 50),
+		fixedDate("Bastille Day", time.July, 14),
+		fixedDate("Assumption Day", time.August, 15),
+		fixedDate("All Saints' Day", time.November, 1),
+		fixedDate("Armistice Day", time.November, 11),
+		fixedDate("Christmas Day", time.December, 25),
+	}},
+	// England and Wales
+	"GB": {nextWeekdaySubstitution, []holidayRule{
+		fixedDate("New Year's Day", time.January, 1),
+		afterEaster("Good Friday", -2),
+		afterEaster("Easter Monday", 1),
+		nthWeekday("Early May Bank Holiday", time.May, time.Monday, 1),
+		nthWeekday("Spring Bank Holiday", time.May, time.Monday, -1),
+		nthWeekday("Summer Bank Holiday", time.August, time.Monday, -1),
+		fixedDate("Christmas Day", time.December, 25),
+		fixedDate("Boxing Day", time.December, 26),
+	}},
+	"IT": {noSubstitution, []holidayRule{
+		fixedDate("New Year's Day", time.January, 1),
+		fixedDate("Epiphany", time.January, 6),
+		afterEaster("Easter Sunday", 0),
+		afterEaster("Easter Monday", 1),
+		fixedDate("Liberation Day", time.April, 25),
+		fixedDate("Labour Day", time.May, 1),
+		fixedDate("Republic Day", time.June, 2),
+		fixedDate("Assumption Day", time.August, 15),
+		fixedDate("All Saints' Day", time.November, 1),
+		fixedDate("Immaculate Conception", time.December, 8),
+		fixedDate("Christmas Day", time.December, 25),
+		fixedDate("St. Stephen's Day", time.December, 26),
+	}},
+	// federal holidays
+	"US": {nearestWeekdaySubstitution, []holidayRule{
+		fixedDate("New Year's Day", time.January, 1),
+		nthWeekday("Martin Luther King Jr. Day", time.January, time.Monday, 3),
+		nthWeekday("Washington's Birthday", time.February, time.Monday, 3),
+		nthWeekday("Memorial Day", time.May, time.Monday, -1),
+		fixedDate("Juneteenth", time.June, 19),
+		fixedDate("Independence Day", time.July, 4),
+		nthWeekday("Labor Day", time.September, time.Monday, 1),
+		nthWeekday("Columbus Day", time.October, time.Monday, 2),
+		fixedDate("Veterans Day", time.November, 11),
+		nthWeekday("Thanksgiving Day", time.November, time.Thursday, 4),
+		fixedDate("Christmas Day", time.December, 25),
+	}},
+}
+
+// codes of the countries with embedded holiday rules, sorted
+func GetHolidayCountryCodes() []string {
+	countryCodes := []string{}
+	for countryCode := range holidayRulesByCountry {
+		countryCodes = append(countryCodes, countryCode)
+	}
+	slices.Sort(countryCodes)
+	return countryCodes
+}
+
+// load holidays from country codes, e.g. IT, or iCalendar files, e.g. holidays.ics, for the days
+// from tMin. Dates of the holidays are in the time zone of tMin
+func LoadHolidays(holidaySources []string, tMin time.Time, noDays int) (HolidayCalendar, error) {
+	holidayCalendar := HolidayCalendar{}
+	tMax := GetPureDateAfterDays(tMin, noDays)
+	for _, holidaySource := range holidaySources {
+		var sourceHolidays HolidayCalendar
+		var err error
+		if strings.HasSuffix(strings.ToLower(holidaySource), ".ics") {
+			sourceHolidays, err = GetHolidaysFromIcsFile(holidaySource, tMin, tMax)
+		} else {
+			// observed dates can move to the year before
+			sourceHolidays, err = GetHolidaysOfCountry(holidaySource, tMin.Year(), tMax.Year()+1)
+		}
+		if err != nil {
+			return nil, err
+		}
+		holidayCalendar.Add(sourceHolidays)
+	}
+	return holidayCalendar, nil
+}
+
+// holidays of a country from the embedded rules, from January 1st of fromYear to December 31st of toYear
+func GetHolidaysOfCountry(countryCode string, fromYear, toYear int) (HolidayCalendar, error) {
+	countryRules, found := holidayRulesByCountry[strings.ToUpper(countryCode)]
+	if !found {
+		return nil, fmt.Errorf("unknown holidays %q, expected an .ics file or one of %s",
+			countryCode, strings.Join(GetHolidayCountryCodes(), ", "))
+	}
+	holidayCalendar := HolidayCalendar{}
+	for year := fromYear; year <= toYear; year++ {
+		for _, rule := range countryRules.rules {
+			actualDate := rule.getDate(year)
+			holidayDate := actualDate
+			switch countryRules.substitution {
+			case nearestWeekdaySubstitution:
+				if holidayDate.Weekday() == time.Saturday {
+					holidayDate = holidayDate.AddDate(0, 0, -1)
+				} else if holidayDate.Weekday() == time.Sunday {
+					holidayDate = holidayDate.AddDate(0, 0, 1)
+				}
+			case nextWeekdaySubstitution:
+				// rules are in order of date, e.g. Boxing Day moves after a substitute Christmas Day
+				for holidayDate.Weekday() == time.Saturday || holidayDate.Weekday() == time.Sunday ||
+					holidayCalendar.IsHoliday(holidayDate) {
+					holidayDate = holidayDate.AddDate(0, 0, 1)
+				}
+			}
+			// the actual date stays a holiday for those working on weekends
+			if !holidayCalendar.IsHoliday(actualDate) {
+				holidayCalendar[actualDate.Format(time.DateOnly)] = rule.name
+			}
+			holidayCalendar[holidayDate.Format(time.DateOnly)] = rule.name
+		}
+	}
+	return holidayCalendar, nil
+}
+
+// holidays from the events of an iCalendar file between tMin and tMax, recurring events included.
+// Each day covered by an event is a holiday named after its summary
+func GetHolidaysFromIcsFile(fileName string, tMin, tMax time.Time) (HolidayCalendar, error) {
+	icsEvents, err := ParseIcsFileInLocation(fileName, tMin.Location())
+	if err != nil {
+		return nil, fmt.Errorf("holidays file %s: %w", fileName, err)
+	}
+	expandedEvents := ExpandIcsEvents(icsEvents, tMin, tMax)
+	holidayCalendar := HolidayCalendar{}
+	for _, icsEvent := range expandedEvents {
+		if icsEvent.Status == "CANCELLED" {
+			continue
+		}
+		// the end is excluded, but events ending when they start cover their day
+		currentDate := GetPureDate(icsEvent.StartTime.In(tMin.Location()))
+		for {
+			if !currentDate.Before(tMin) && currentDate.Before(tMax) {
+				holidayCalendar[currentDate.Format(time.DateOnly)] = icsEvent.Summary
+			}
+			currentDate = GetPureDateAfterDays(currentDate, 1)
+			if !currentDate.Before(icsEvent.EndTime) {
+				break
+			}
+		}
+	}
+	return holidayCalendar, nil
+}
+
+// date of the holiday in the given year, in UTC
+func (rule holidayRule) getDate(year int) time.Time {
+	switch rule.kind {
+	case easterRule:
+		return GetEasterSunday(year).AddDate(0, 0, rule.easterOffset)
+	case nthWeekdayRule:
+		if rule.nth < 0 {
+			// back from the last day of the month
+			lastDay := time.Date(year, rule.month+1, 0, 0, 0, 0, 0, time.UTC)
+			return lastDay.AddDate(0, 0, -((int(lastDay.Weekday())-int(rule.weekday)+7)%7 + 7*(-rule.nth-1)))
+		}
+		firstDay := time.Date(year, rule.month, 1, 0, 0, 0, 0, time.UTC)
+		return firstDay.AddDate(0, 0, (int(rule.weekday)-int(firstDay.Weekday())+7)%7+7*(rule.nth-1))
+	}
+	return time.Date(year, rule.month, rule.day, 0, 0, 0, 0, time.UTC)
+}
+
+// Easter Sunday of the Gregorian calendar, in UTC (anonymous Gregorian algorithm)
+func GetEasterSunday(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// add the holidays of another calendar, keeping the names already set
+func (holidayCalendar HolidayCalendar) Add(otherCalendar HolidayCalendar) {
+	for date, name := range otherCalendar {
+		if _, found := holidayCalendar[date]; !found {
+			holidayCalendar[date] = name
+		}
+	}
+}
+
+func (holidayCalendar HolidayCalendar) IsHoliday(date time.Time) bool {
+	_, found := holidayCalendar[date.Format(time.DateOnly)]
+	return found
+}
+
+// name of the holiday on the date of the wall clock of date, empty if not a holiday
+func (holidayCalendar HolidayCalendar) GetHolidayName(date time.Time) string {
+	return holidayCalendar[date.Format(time.DateOnly)]
+}
+
+// synthetic event covering the whole day, to list a holiday among the events
+func (holidayCalendar HolidayCalendar) getHolidayEvent(date time.Time) CalendarEvent {
+	return CalendarEvent{
+		StartTime:   date,
+		Duration:    int(GetPureDateAfterDays(date, 1).Sub(date).Minutes()),
+		Description: "holiday: " + holidayCalendar.GetHolidayName(date),
+		Timezone:    date.Location().String(),
+		CalendarId:  HolidayCalendarId,
+		AllDay:      true,
+	}
+}
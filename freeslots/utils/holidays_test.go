@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetEasterSunday(t *testing.T) {
+	expectedDates := map[int]string{
+		2019: "2019-04-21",
+		2024: "2024-03-31",
+		2025: "2025-04-20",
+		2026: "2026-04-05",
+		2038: "2038-04-25",
+	}
+	for year, expectedDate := range expectedDates {
+		if easterSunday := GetEasterSunday(year).Format(time.DateOnly); easterSunday != expectedDate {
+			t.Errorf("Wrong Easter Sunday for %v: %v", year, easterSunday)
+		}
+	}
+}
+
+func TestGetHolidaysOfCountry(t *testing.T) {
+	testCases := []struct {
+		countryCode string
+		year        int
+		holidays    map[string]string
+		workingDays []string
+	}{
+		{"IT", 2025, map[string]string{"2025-04-21": "Easter Monday", "2025-06-02": "Republic Day", "2025-12-26": "St. Stephen's Day"},
+			[]string{"2025-06-03"}},
+		// Independence Day on Saturday is observed on Friday, and it is still a holiday on Saturday
+		{"US", 2026, map[string]string{"2026-07-03": "Independence Day", "2026-07-04": "Independence Day", "2026-05-25": "Memorial Day",
+			"2026-11-26": "Thanksgiving Day", "2026-01-19": "Martin Luther King Jr. Day"},
+			[]string{"2026-07-02", "2026-07-05"}},
+		// Christmas Day on Saturday and Boxing Day on Sunday are observed on Monday and Tuesday
+		{"gb", 2027, map[string]string{"2027-12-25": "Christmas Day", "2027-12-26": "Boxing Day", "2027-12-27": "Christmas Day",
+			"2027-12-28": "Boxing Day", "2027-08-30": "Summer Bank Holiday", "2027-03-26": "Good Friday"},
+			[]string{"2027-12-24", "2027-12-29"}},
+		{"DE", 2025, map[string]string{"2025-05-29": "Ascension Day", "2025-06-09": "Whit Monday"}, []string{"2025-06-08"}},
+	}
+	for _, testCase := range testCases {
+		holidayCalendar, err := GetHolidaysOfCountry(testCase.countryCode, testCase.year, testCase.year)
+		if err != nil {
+			t.Errorf("Error while getting holidays of %v: %v", testCase.countryCode, err)
+			continue
+		}
+		for date, name := range testCase.holidays {
+			if holidayCalendar[date] != name {
+				t.Errorf("%v: wrong holiday on %v: %q", testCase.countryCode, date, holidayCalendar[date])
+			}
+		}
+		for _, date := range testCase.workingDays {
+			if _, found := holidayCalendar[date]; found {
+				t.Errorf("%v: unexpected holiday on %v", testCase.countryCode, date)
+			}
+		}
+	}
+
+	// New Year's Day 2028 is on Saturday, observed in 2027
+	holidayCalendar, _ := LoadHolidays([]string{"US"}, time.Date(2027, time.December, 20, 0, 0, 0, 0, time.UTC), 14)
+	if holidayCalendar["2027-12-31"] != "New Year's Day" {
+		t.Errorf("Observed New Year's Day not found")
+	}
+
+	if _, err := LoadHolidays([]string{"XX"}, time.Now(), 1); err == nil {
+		t.Errorf("No error for unknown holidays")
+	}
+}
+
+func TestGetHolidaysFromIcsFile(t *testing.T) {
+	icsCalendar := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nUID:1\r\nDTSTART;VALUE=DATE:20251224\r\nDTEND;VALUE=DATE:20251227\r\nSUMMARY:Company closure\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:2\r\nDTSTART;VALUE=DATE:20200101\r\nRRULE:FREQ=YEARLY\r\nSUMMARY:New Year\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	fileName := filepath.Join(t.TempDir(), "holidays.ics")
+	os.WriteFile(fileName, []byte(icsCalendar), 0644)
+	rome, _ := time.LoadLocation("Europe/Rome")
+	holidayCalendar, err := LoadHolidays([]string{fileName}, time.Date(2025, time.December, 22, 0, 0, 0, 0, rome), 14)
+	if err != nil {
+		t.Errorf("Error while loading holidays: %v", err)
+		return
+	}
+	expectedHolidays := map[string]string{
+		"2025-12-24": "Company closure",
+		"2025-12-25": "Company closure",
+		"2025-12-26": "Company closure",
+		"2026-01-01": "New Year",
+	}
+	if len(holidayCalendar) != len(expectedHolidays) {
+		t.Errorf("Wrong holidays: %v", holidayCalendar)
+	}
+	for date, name := range expectedHolidays {
+		if holidayCalendar[date] != name {
+			t.Errorf("Wrong holiday on %v: %q", date, holidayCalendar[date])
+		}
+	}
+}
+
+func TestFreeSlotsCoreWithHolidays(t *testing.T) {
+	rome, _ := time.LoadLocation("Europe/Rome")
+	startDate := time.Date(2025, time.December, 24, 0, 0, 0, 0, rome)
+	holidayCalendar, _ := LoadHolidays([]string{"IT"}, startDate, 3)
+	dailyAgenda, _ := ParseDailyAgendaInLocation("d2025-12-26,m60,s10,aX", rome)
+	freeSlotsCoreAlgorithm := FreeSlotsCoreAlgorithm{
+		NoDays:    3,
+		FromTime:  "09:00",
+		ToTime:    "18:00",
+		Format:    "plain",
+		StartDate: startDate,
+		Holidays:  holidayCalendar,
+	}
+
+	var output bytes.Buffer
+	freeSlotsCoreAlgorithm.Output = &output
+	if err := freeSlotsCoreAlgorithm.FreeSlotsCore([]DailyAgenda{dailyAgenda}); err != nil {
+		t.Errorf("Error while getting free slots: %v", err)
+		return
+	}
+	if output.String() != "24 Dec 2025: 09:00-18:00 CET\n" {
+		t.Errorf("Unexpected free slots: %q", output.String())
+	}
+
+	// holidays are listed among the events
+	output.Reset()
+	freeSlotsCoreAlgorithm.ShowAllEvents = true
+	if err := freeSlotsCoreAlgorithm.FreeSlotsCore([]DailyAgenda{dailyAgenda}); err != nil {
+		t.Errorf("Error while getting all events: %v", err)
+		return
+	}
+	expectedOutput := "25 Dec 2025: all day (holiday: Christmas Day)\n" +
+		"26 Dec 2025: all day (holiday: St. Stephen's Day), 10:00-11:00 CET (X)\n"
+	if output.String() != expectedOutput {
+		t.Errorf("Unexpected events: %q", output.String())
+	}
+}
@@ -0,0 +1,465 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VEVENT read from an iCalendar (RFC 5545) stream
+type IcsEvent struct {
+	Uid            string
+	Summary        string
+	Location       string
+	VideoLink      string
+	Status         string
+	Transparency   string
+	StartTime      time.Time
+	EndTime        time.Time
+	AllDay         bool
+	RecurrenceRule string
+	ExceptionDates []time.Time
+	RecurrenceId   time.Time
+}
+
+// single content line of an iCalendar stream, e.g. "DTSTART;TZID=Europe/Rome:20251210T090000"
+type icsContentLine struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// convert iCalendar events overlapping the [tMin, tMax) range into busy calendar events sorted by start time
+func ConvertIcsEventsToCalendarEvents(icsEvents []IcsEvent, tMin, tMax time.Time, calendarId string) []CalendarEvent {
+	eventList := []CalendarEvent{}
+	for _, icsEvent := range icsEvents {
+		// cancelled events are not shown at all, events marked as free are left to BusyPolicy
+		if icsEvent.Status == "CANCELLED" {
+			continue
+		}
+		if !icsEvent.EndTime.After(tMin) || !icsEvent.StartTime.Before(tMax) {
+			continue
+		}
+		newEvent := icsEvent.ToCalendarEvent()
+		newEvent.CalendarId = calendarId
+		// events are split into days in the time zone of the requested range
+		newEvent.StartTime = newEvent.StartTime.In(tMin.Location())
+		eventList = append(eventList, newEvent)
+	}
+	SortEventListByStartTime(&eventList)
+	return eventList
+}
+
+func (icsEvent IcsEvent) ToCalendarEvent() CalendarEvent {
+	return CalendarEvent{
+		StartTime:   icsEvent.StartTime,
+		Duration:    int(icsEvent.EndTime.Sub(icsEvent.StartTime).Minutes()),
+		Description: icsEvent.Summary,
+		Timezone:    icsEvent.StartTime.Location().String(),
+		Place:       icsEvent.Location,
+		VideoLink:   icsEvent.VideoLink,
+		AllDay:      icsEvent.AllDay,
+		// same values of the Google Calendar API
+		Status:       strings.ToLower(icsEvent.Status),
+		Transparency: strings.ToLower(icsEvent.Transparency),
+	}
+}
+
+func ParseIcsFile(fileName string) ([]IcsEvent, error) {
+	return ParseIcsFileInLocation(fileName, time.Local)
+}
+
+// parse an iCalendar file, floating times are in the given location
+func ParseIcsFileInLocation(fileName string, location *time.Location) ([]IcsEvent, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseIcsInLocation(f, location)
+}
+
+// parse the VEVENT components of an iCalendar stream
+func ParseIcs(reader io.Reader) ([]IcsEvent, error) {
+	return ParseIcsInLocation(reader, time.Local)
+}
+
+// parse the VEVENT components of an iCalendar stream, floating times are in the given location.
+// VTIMEZONE components are not read: their TZID must be an IANA or a Windows time zone name
+func ParseIcsInLocation(reader io.Reader, location *time.Location) ([]IcsEvent, error) {
+	contentLines, err := readIcsContentLines(reader)
+	if err != nil {
+		return nil, err
+	}
+	warner := &warner{}
+	timezoneComponents, err := getIcsComponents(contentLines, "VTIMEZONE")
+	if err != nil {
+		return nil, err
+	}
+	for _, timezoneLines := range timezoneComponents {
+		for _, contentLine := range timezoneLines {
+			if contentLine.name != "TZID" {
+				continue
+			}
+			if _, err := loadIcsLocation(contentLine.value); err != nil {
+				warner.warn("the VTIMEZONE of time zone %q is not supported, its times are read as floating", contentLine.value)
+			}
+		}
+	}
+	components, err := getIcsComponents(contentLines, "VEVENT")
+	if err != nil {
+		return nil, err
+	}
+	icsEvents := []IcsEvent{}
+	for _, eventLines := range components {
+		icsEvent, err := parseIcsEvent(eventLines, location, warner)
+		if err != nil {
+			return nil, err
+		}
+		icsEvents = append(icsEvents, icsEvent)
+	}
+	return icsEvents, nil
+}
+
+// parse the busy periods of the VFREEBUSY components of an iCalendar stream.
+// Each FREEBUSY period not marked as FBTYPE=FREE becomes an event, tentative if marked as FBTYPE=BUSY-TENTATIVE
+func ParseIcsFreeBusy(reader io.Reader) ([]IcsEvent, error) {
+	contentLines, err := readIcsContentLines(reader)
+	if err != nil {
+		return nil, err
+	}
+	components, err := getIcsComponents(contentLines, "VFREEBUSY")
+	if err != nil {
+		return nil, err
+	}
+	icsEvents := []IcsEvent{}
+	for _, freeBusyLines := range components {
+		for _, contentLine := range freeBusyLines {
+			if contentLine.name != "FREEBUSY" || strings.ToUpper(contentLine.params["FBTYPE"]) == "FREE" {
+				continue
+			}
+			status := ""
+			if strings.ToUpper(contentLine.params["FBTYPE"]) == "BUSY-TENTATIVE" {
+				status = "TENTATIVE"
+			}
+			for _, period := range strings.Split(contentLine.value, ",") {
+				startTime, endTime, err := parseIcsPeriod(period)
+				if err != nil {
+					return nil, err
+				}
+				icsEvents = append(icsEvents, IcsEvent{
+					Summary:   "busy",
+					Status:    status,
+					StartTime: startTime,
+					EndTime:   endTime,
+				})
+			}
+		}
+	}
+	return icsEvents, nil
+}
+
+// return the properties of each component with the given name, e.g. VEVENT.
+// Properties of nested components (e.g. VALARM) are ignored
+func getIcsComponents(contentLines []icsContentLine, componentName string) ([][]icsContentLine, error) {
+	components := [][]icsContentLine{}
+	var componentLines []icsContentLine
+	// nesting of the components, e.g. VCALENDAR > VEVENT > VALARM
+	nesting := []string{}
+	for _, contentLine := range contentLines {
+		switch contentLine.name {
+		case "BEGIN":
+			nesting = append(nesting, strings.ToUpper(contentLine.value))
+			if strings.ToUpper(contentLine.value) == componentName {
+				componentLines = []icsContentLine{}
+			}
+			continue
+		case "END":
+			if len(nesting) == 0 || nesting[len(nesting)-1] != strings.ToUpper(contentLine.value) {
+				return nil, fmt.Errorf("unexpected END:%s", contentLine.value)
+			}
+			nesting = nesting[:len(nesting)-1]
+			if strings.ToUpper(contentLine.value) == componentName {
+				components = append(components, componentLines)
+				componentLines = nil
+			}
+			continue
+		}
+		if len(nesting) > 0 && nesting[len(nesting)-1] == componentName {
+			componentLines = append(componentLines, contentLine)
+		}
+	}
+	if len(nesting) > 0 {
+		return nil, fmt.Errorf("missing END:%s", nesting[len(nesting)-1])
+	}
+	return components, nil
+}
+
+func parseIcsEvent(eventLines []icsContentLine, location *time.Location, warner *warner) (IcsEvent, error) {
+	icsEvent := IcsEvent{}
+	var durationDays int
+	var duration time.Duration
+	hasEndTime, hasDuration := false, false
+	for _, contentLine := range eventLines {
+		var err error
+		switch contentLine.name {
+		case "UID":
+			icsEvent.Uid = contentLine.value
+		case "SUMMARY":
+			icsEvent.Summary = unescapeIcsText(contentLine.value)
+		case "LOCATION":
+			icsEvent.Location = unescapeIcsText(contentLine.value)
+		case "CONFERENCE", "X-GOOGLE-CONFERENCE":
+			icsEvent.VideoLink = contentLine.value
+		case "STATUS":
+			icsEvent.Status = strings.ToUpper(contentLine.value)
+		case "TRANSP":
+			icsEvent.Transparency = strings.ToUpper(contentLine.value)
+		case "DTSTART":
+			icsEvent.StartTime, icsEvent.AllDay, err = parseIcsDateTime(contentLine.value, contentLine.params, location, warner)
+		case "DTEND":
+			icsEvent.EndTime, _, err = parseIcsDateTime(contentLine.value, contentLine.params, location, warner)
+			hasEndTime = true
+		case "DURATION":
+			durationDays, duration, err = parseIcsNominalDuration(contentLine.value)
+			hasDuration = true
+		case "RRULE":
+			icsEvent.RecurrenceRule = contentLine.value
+		case "EXDATE":
+			for _, exceptionValue := range strings.Split(contentLine.value, ",") {
+				var exceptionDate time.Time
+				exceptionDate, _, err = parseIcsDateTime(exceptionValue, contentLine.params, location, warner)
+				if err != nil {
+					break
+				}
+				icsEvent.ExceptionDates = append(icsEvent.ExceptionDates, exceptionDate)
+			}
+		case "RECURRENCE-ID":
+			icsEvent.RecurrenceId, _, err = parseIcsDateTime(contentLine.value, contentLine.params, location, warner)
+		}
+		if err != nil {
+			return IcsEvent{}, fmt.Errorf("event %s: %s: %w", icsEvent.Uid, contentLine.name, err)
+		}
+	}
+	if icsEvent.StartTime.IsZero() {
+		return IcsEvent{}, fmt.Errorf("event %s: missing DTSTART", icsEvent.Uid)
+	}
+	switch {
+	case hasEndTime:
+		// DTEND already set
+	case hasDuration && icsEvent.AllDay:
+		// nominal days, independent from DST. RFC 5545 allows only days and weeks here
+		durationDays += int(duration / (24 * time.Hour))
+		if duration%(24*time.Hour) != 0 {
+			warner.warn("event %s: DURATION %v of an all-day event is not in days, rounded up", icsEvent.Uid, duration)
+			durationDays++
+		}
+		icsEvent.EndTime = GetPureDateAfterDays(icsEvent.StartTime, durationDays)
+	case hasDuration:
+		// days are nominal, so that P1D ends at the same time of the next day across DST transitions
+		startTime := icsEvent.StartTime
+		icsEvent.EndTime = GetWallClockTime(startTime.Year(), startTime.Month(), startTime.Day()+durationDays,
+			startTime.Hour(), startTime.Minute(), startTime.Location()).Add(time.Duration(startTime.Second())*time.Second + duration)
+	case icsEvent.AllDay:
+		// RFC 5545: an all-day event without DTEND lasts one day
+		icsEvent.EndTime = GetPureDateAfterDays(icsEvent.StartTime, 1)
+	default:
+		icsEvent.EndTime = icsEvent.StartTime
+	}
+	return icsEvent, nil
+}
+
+// read content lines, unfolding lines split over multiple physical lines
+func readIcsContentLines(reader io.Reader) ([]icsContentLine, error) {
+	unfoldedLines := []string{}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(unfoldedLines) > 0 {
+			unfoldedLines[len(unfoldedLines)-1] += line[1:]
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		unfoldedLines = append(unfoldedLines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	contentLines := make([]icsContentLine, 0, len(unfoldedLines))
+	for _, unfoldedLine := range unfoldedLines {
+		contentLine, err := parseIcsContentLine(unfoldedLine)
+		if err != nil {
+			return nil, err
+		}
+		contentLines = append(contentLines, contentLine)
+	}
+	return contentLines, nil
+}
+
+// parse a line in the form NAME;PARAM1=VALUE1;PARAM2="VALUE:2":VALUE
+func parseIcsContentLine(line string) (icsContentLine, error) {
+	contentLine := icsContentLine{params: map[string]string{}}
+	inQuotes := false
+	nameEnd := -1
+	valueStart := -1
+	for index, char := range line {
+		switch {
+		case char == '"':
+			inQuotes = !inQuotes
+		case char == ';' && !inQuotes && nameEnd < 0:
+			nameEnd = index
+		case char == ':' && !inQuotes:
+			valueStart = index
+		}
+		if valueStart >= 0 {
+			break
+		}
+	}
+	if valueStart < 0 {
+		return icsContentLine{}, fmt.Errorf("bad content line: %q", line)
+	}
+	if nameEnd < 0 {
+		nameEnd = valueStart
+	}
+	contentLine.name = strings.ToUpper(line[:nameEnd])
+	contentLine.value = line[valueStart+1:]
+	if nameEnd < valueStart {
+		for _, param := range splitOutsideQuotes(line[nameEnd+1:valueStart], ';') {
+			paramName, paramValue, _ := strings.Cut(param, "=")
+			contentLine.params[strings.ToUpper(paramName)] = strings.Trim(paramValue, "\"")
+		}
+	}
+	return contentLine, nil
+}
+
+func splitOutsideQuotes(text string, separator rune) []string {
+	parts := []string{}
+	inQuotes := false
+	partStart := 0
+	for index, char := range text {
+		switch {
+		case char == '"':
+			inQuotes = !inQuotes
+		case char == separator && !inQuotes:
+			parts = append(parts, text[partStart:index])
+			partStart = index + 1
+		}
+	}
+	return append(parts, text[partStart:])
+}
+
+// parse a DATE or DATE-TIME value, returning true if the value is a DATE (all-day)
+// DATE-TIME values can be in UTC (trailing Z), in the time zone of the TZID parameter or floating (in the given location).
+// Unknown time zones are read as floating times, with a warning
+func parseIcsDateTime(value string, params map[string]string, location *time.Location, warner *warner) (time.Time, bool, error) {
+	if tzid, found := params["TZID"]; found {
+		tzLocation, err := loadIcsLocation(tzid)
+		if err != nil {
+			warner.warn("%v, using %v", err, location)
+		} else {
+			location = tzLocation
+		}
+	}
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		parsedTime, err := ParseDateInLocation("20060102", value, location)
+		return parsedTime, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		parsedTime, err := time.Parse("20060102T150405Z", value)
+		return parsedTime, false, err
+	}
+	parsedTime, err := time.ParseInLocation("20060102T150405", value, location)
+	return parsedTime, false, err
+}
+
+// parse a PERIOD value, either "start/end" or "start/duration"
+// RFC 5545: periods of FREEBUSY properties are in UTC
+func parseIcsPeriod(value string) (time.Time, time.Time, error) {
+	startValue, endValue, found := strings.Cut(value, "/")
+	if !found {
+		return time.Time{}, time.Time{}, fmt.Errorf("bad period %q", value)
+	}
+	startTime, _, err := parseIcsDateTime(startValue, map[string]string{}, time.UTC, &warner{})
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if strings.HasPrefix(endValue, "P") || strings.HasPrefix(endValue, "+P") {
+		duration, err := parseIcsDuration(endValue)
+		return startTime, startTime.Add(duration), err
+	}
+	endTime, _, err := parseIcsDateTime(endValue, map[string]string{}, time.UTC, &warner{})
+	return startTime, endTime, err
+}
+
+// parse a DURATION value, e.g. "PT1H30M", "P1D", "P2W", "-PT15M", with days as 24 hours
+func parseIcsDuration(value string) (time.Duration, error) {
+	days, duration, err := parseIcsNominalDuration(value)
+	return time.Duration(days)*24*time.Hour + duration, err
+}
+
+// parse a DURATION value into nominal days, weeks included, and the exact duration of its time part
+func parseIcsNominalDuration(value string) (int, time.Duration, error) {
+	text := value
+	sign := 1
+	switch {
+	case strings.HasPrefix(text, "-"):
+		sign = -1
+		text = text[1:]
+	case strings.HasPrefix(text, "+"):
+		text = text[1:]
+	}
+	if !strings.HasPrefix(text, "P") || len(text) < 3 {
+		return 0, 0, fmt.Errorf("bad duration %q", value)
+	}
+	text = text[1:]
+	days := 0
+	var duration time.Duration
+	inTimePart := false
+	number := ""
+	for _, char := range text {
+		if char >= '0' && char <= '9' {
+			number += string(char)
+			continue
+		}
+		if char == 'T' {
+			inTimePart = true
+			continue
+		}
+		amount, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, 0, fmt.Errorf("bad duration %q", value)
+		}
+		number = ""
+		switch {
+		case char == 'W' && !inTimePart:
+			days += amount * 7
+		case char == 'D' && !inTimePart:
+			days += amount
+		case char == 'H' && inTimePart:
+			duration += time.Duration(amount) * time.Hour
+		case char == 'M' && inTimePart:
+			duration += time.Duration(amount) * time.Minute
+		case char == 'S' && inTimePart:
+			duration += time.Duration(amount) * time.Second
+		default:
+			return 0, 0, fmt.Errorf("bad duration %q", value)
+		}
+	}
+	if number != "" {
+		return 0, 0, fmt.Errorf("bad duration %q", value)
+	}
+	return sign * days, time.Duration(sign) * duration, nil
+}
+
+// unescape a TEXT value
+func unescapeIcsText(value string) string {
+	replacer := strings.NewReplacer(`\\`, `\`, `\;`, `;`, `\,`, `,`, `\n`, "\n", `\N`, "\n")
+	return replacer.Replace(value)
+}
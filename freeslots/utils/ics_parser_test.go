@@ -0,0 +1,195 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testIcsCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//freeslots//test//EN\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:1\r\n" +
+	"DTSTART;TZID=Europe/Rome:20251210T090000\r\n" +
+	"DTEND;TZID=Europe/Rome:20251210T100000\r\n" +
+	"SUMMARY:Weekly sync\\, team\r\n" +
+	"LOCATION:Room 2\\, first floor\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"TRIGGER:-PT15M\r\n" +
+	"DESCRIPTION:Reminder\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:2\r\n" +
+	"DTSTART:20251210T130000Z\r\n" +
+	"DURATION:PT1H30M\r\n" +
+	"SUMMARY:A very long summary that is folded\r\n" +
+	"  over two lines\r\n" +
+	"X-GOOGLE-CONFERENCE:https://meet.google.com/abc-defg-hij\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:3\r\n" +
+	"DTSTART;VALUE=DATE:20251211\r\n" +
+	"SUMMARY:All day\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:4\r\n" +
+	"DTSTART;TZID=\"Europe/Rome\":20251211T090000\r\n" +
+	"DTEND;TZID=\"Europe/Rome\":20251211T100000\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"SUMMARY:Cancelled\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:5\r\n" +
+	"DTSTART;TZID=Europe/Rome:20251211T110000\r\n" +
+	"DTEND;TZID=Europe/Rome:20251211T120000\r\n" +
+	"TRANSP:TRANSPARENT\r\n" +
+	"SUMMARY:Show as free\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:6\r\n" +
+	"DTSTART;TZID=Europe/Rome:20251220T110000\r\n" +
+	"DTEND;TZID=Europe/Rome:20251220T120000\r\n" +
+	"SUMMARY:Out of range\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseIcs(t *testing.T) {
+	icsEvents, err := ParseIcs(strings.NewReader(testIcsCalendar))
+	if err != nil {
+		t.Errorf("Error while parsing: %v", err)
+		return
+	}
+	if len(icsEvents) != 6 {
+		t.Errorf("Length mismatch about no. events: %v", len(icsEvents))
+		return
+	}
+	rome, _ := time.LoadLocation("Europe/Rome")
+	expectedStartTimes := []time.Time{
+		time.Date(2025, time.December, 10, 9, 0, 0, 0, rome),
+		time.Date(2025, time.December, 10, 13, 0, 0, 0, time.UTC),
+		time.Date(2025, time.December, 11, 0, 0, 0, 0, time.Local),
+	}
+	expectedDurations := []time.Duration{time.Hour, 90 * time.Minute, 24 * time.Hour}
+	expectedSummaries := []string{"Weekly sync, team", "A very long summary that is folded over two lines", "All day"}
+	for eventIndex, expectedStartTime := range expectedStartTimes {
+		icsEvent := icsEvents[eventIndex]
+		if !icsEvent.StartTime.Equal(expectedStartTime) ||
+			icsEvent.EndTime.Sub(icsEvent.StartTime) != expectedDurations[eventIndex] ||
+			icsEvent.Summary != expectedSummaries[eventIndex] {
+			t.Errorf("Error while parsing: mismatching event index %v: %v", eventIndex, icsEvent)
+		}
+	}
+	if !icsEvents[2].AllDay || icsEvents[0].AllDay {
+		t.Errorf("Error while parsing all-day events")
+	}
+	if icsEvents[3].Status != "CANCELLED" || icsEvents[4].Transparency != "TRANSPARENT" {
+		t.Errorf("Error while parsing status and transparency")
+	}
+
+	tMin := time.Date(2025, time.December, 10, 0, 0, 0, 0, rome)
+	calendarEvents := ConvertIcsEventsToCalendarEvents(icsEvents, tMin, tMin.AddDate(0, 0, 7), "test.ics")
+	// cancelled and out of range events are dropped, transparent ones are left to BusyPolicy
+	if len(calendarEvents) != 4 || calendarEvents[3].Transparency != TransparencyTransparent || calendarEvents[0].Duration != 60 || calendarEvents[1].Duration != 90 ||
+		calendarEvents[0].AllDay || !calendarEvents[2].AllDay ||
+		calendarEvents[0].CalendarId != "test.ics" || calendarEvents[0].Place != "Room 2, first floor" ||
+		calendarEvents[1].VideoLink != "https://meet.google.com/abc-defg-hij" {
+		t.Errorf("Error while converting events")
+		PrintEventList(calendarEvents)
+	}
+}
+
+func TestParseIcsDuration(t *testing.T) {
+	durations := []string{"PT1H30M", "P1D", "P2W", "-PT15M", "P1DT12H", "PT45S"}
+	expectedDurations := []time.Duration{
+		90 * time.Minute,
+		24 * time.Hour,
+		14 * 24 * time.Hour,
+		-15 * time.Minute,
+		36 * time.Hour,
+		45 * time.Second,
+	}
+	for durationIndex, duration := range durations {
+		parsedDuration, err := parseIcsDuration(duration)
+		if err != nil || parsedDuration != expectedDurations[durationIndex] {
+			t.Errorf("Error while parsing duration %v: %v %v", duration, parsedDuration, err)
+		}
+	}
+	for _, badDuration := range []string{"", "P", "1H", "PT1X", "PT1"} {
+		if _, err := parseIcsDuration(badDuration); err == nil {
+			t.Errorf("Error expected while parsing duration %v", badDuration)
+		}
+	}
+}
+
+func TestParseIcsOutlookTimeZones(t *testing.T) {
+	var warnings strings.Builder
+	defaultWarningOutput := warningOutput
+	defer func() { warningOutput = defaultWarningOutput }()
+	warningOutput = &warnings
+
+	icsCalendar := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VTIMEZONE\r\nTZID:W. Europe Standard Time\r\nBEGIN:STANDARD\r\nDTSTART:16010101T030000\r\nTZOFFSETFROM:+0200\r\nTZOFFSETTO:+0100\r\nEND:STANDARD\r\nEND:VTIMEZONE\r\n" +
+		"BEGIN:VTIMEZONE\r\nTZID:Customized Time Zone\r\nBEGIN:STANDARD\r\nDTSTART:16010101T000000\r\nTZOFFSETFROM:+0530\r\nTZOFFSETTO:+0530\r\nEND:STANDARD\r\nEND:VTIMEZONE\r\n" +
+		"BEGIN:VEVENT\r\nUID:windows\r\nDTSTART;TZID=W. Europe Standard Time:20251210T090000\r\nDTEND;TZID=W. Europe Standard Time:20251210T100000\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:quoted\r\nDTSTART;TZID=\"Eastern Standard Time\":20251210T090000\r\nDTEND;TZID=\"Eastern Standard Time\":20251210T100000\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:vendor\r\nDTSTART;TZID=/mozilla.org/20050126_1/Asia/Tokyo:20251210T090000\r\nDTEND;TZID=/mozilla.org/20050126_1/Asia/Tokyo:20251210T100000\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:custom\r\nDTSTART;TZID=Customized Time Zone:20251210T090000\r\nDTEND;TZID=Customized Time Zone:20251210T100000\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	icsEvents, err := ParseIcsInLocation(strings.NewReader(icsCalendar), time.UTC)
+	if err != nil {
+		t.Errorf("Error while parsing: %v", err)
+		return
+	}
+	expectedStartTimes := []string{"2025-12-10T08:00:00Z", "2025-12-10T14:00:00Z", "2025-12-10T00:00:00Z", "2025-12-10T09:00:00Z"}
+	if len(icsEvents) != len(expectedStartTimes) {
+		t.Errorf("Length mismatch about no. events: %v", len(icsEvents))
+		return
+	}
+	for eventIndex, icsEvent := range icsEvents {
+		if startTime := icsEvent.StartTime.UTC().Format(time.RFC3339); startTime != expectedStartTimes[eventIndex] {
+			t.Errorf("Wrong start time of %v: %v", icsEvent.Uid, startTime)
+		}
+	}
+	// the unknown time zone is reported once, and read as floating time
+	if warnings.String() != "warning: the VTIMEZONE of time zone \"Customized Time Zone\" is not supported, its times are read as floating\n"+
+		"warning: unknown time zone \"Customized Time Zone\", using UTC\n" {
+		t.Errorf("Unexpected warnings: %q", warnings.String())
+	}
+}
+
+func TestParseIcsNominalDurations(t *testing.T) {
+	var warnings strings.Builder
+	defaultWarningOutput := warningOutput
+	defer func() { warningOutput = defaultWarningOutput }()
+	warningOutput = &warnings
+
+	// DST starts in Rome on 30 Mar 2025
+	icsCalendar := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nUID:day\r\nDTSTART;TZID=Europe/Rome:20250329T100000\r\nDURATION:P1D\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:hours\r\nDTSTART;TZID=Europe/Rome:20250329T100000\r\nDURATION:PT24H\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:mixed\r\nDTSTART;TZID=Europe/Rome:20250329T100000\r\nDURATION:P1DT2H\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:allday\r\nDTSTART;VALUE=DATE:20250329\r\nDURATION:PT36H\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	rome, _ := time.LoadLocation("Europe/Rome")
+	icsEvents, err := ParseIcsInLocation(strings.NewReader(icsCalendar), rome)
+	if err != nil {
+		t.Errorf("Error while parsing: %v", err)
+		return
+	}
+	expectedEndTimes := []string{"2025-03-30T10:00:00+02:00", "2025-03-30T11:00:00+02:00", "2025-03-30T12:00:00+02:00", "2025-03-31T00:00:00+02:00"}
+	if len(icsEvents) != len(expectedEndTimes) {
+		t.Errorf("Length mismatch about no. events: %v", len(icsEvents))
+		return
+	}
+	for eventIndex, icsEvent := range icsEvents {
+		if endTime := icsEvent.EndTime.In(rome).Format(time.RFC3339); endTime != expectedEndTimes[eventIndex] {
+			t.Errorf("Wrong end time of %v: %v", icsEvent.Uid, endTime)
+		}
+	}
+	// the part of a day of an all-day event is not dropped silently
+	if !strings.Contains(warnings.String(), "event allday: DURATION 36h0m0s of an all-day event is not in days, rounded up") {
+		t.Errorf("Unexpected warnings: %q", warnings.String())
+	}
+}
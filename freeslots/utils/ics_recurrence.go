@@ -0,0 +1,380 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// max number of periods (days, weeks, months or years) scanned while expanding a recurrence rule
+const maxRecurrencePeriods = 100000
+
+// RRULE of a recurring event (RFC 5545, section 3.3.10)
+// Supported rule parts: FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, COUNT, UNTIL,
+// BYDAY, BYMONTHDAY, BYMONTH and WKST
+type RecurrenceRule struct {
+	Frequency  string
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []RecurrenceWeekday
+	ByMonthDay []int
+	ByMonth    []time.Month
+	WeekStart  time.Weekday
+}
+
+// week day of a BYDAY rule part, e.g. "MO", "1FR" (first Friday) or "-1SU" (last Sunday)
+// Ordinal is 0 when every week day of the period is selected
+type RecurrenceWeekday struct {
+	Ordinal int
+	Weekday time.Weekday
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// expand recurring events into single occurrences overlapping the [tMin, tMax) range.
+// Occurrences listed in EXDATE are dropped and occurrences overridden by an event with
+// the same UID and a RECURRENCE-ID are replaced by the overriding event.
+// Non-recurring events are returned as they are. Events whose RRULE is not supported keep
+// only their first occurrence, with a warning, so that one such event doesn't spoil a calendar
+func ExpandIcsEvents(icsEvents []IcsEvent, tMin, tMax time.Time) []IcsEvent {
+	// overridden occurrences by UID
+	overriddenOccurrences := make(map[string][]time.Time)
+	for _, icsEvent := range icsEvents {
+		if !icsEvent.RecurrenceId.IsZero() {
+			overriddenOccurrences[icsEvent.Uid] = append(overriddenOccurrences[icsEvent.Uid], icsEvent.RecurrenceId)
+		}
+	}
+	expandedEvents := []IcsEvent{}
+	warner := &warner{}
+	for _, icsEvent := range icsEvents {
+		if icsEvent.RecurrenceRule == "" || !icsEvent.RecurrenceId.IsZero() {
+			expandedEvents = append(expandedEvents, icsEvent)
+			continue
+		}
+		occurrenceStartTimes := []time.Time{icsEvent.StartTime}
+		recurrenceRule, err := ParseRecurrenceRule(icsEvent.RecurrenceRule, icsEvent.StartTime.Location())
+		if err != nil {
+			warner.warn("event %s %q: %v, only its first occurrence is used", icsEvent.Uid, icsEvent.Summary, err)
+		} else {
+			occurrenceStartTimes = recurrenceRule.Occurrences(icsEvent.StartTime, tMax)
+		}
+		for _, occurrenceStartTime := range occurrenceStartTimes {
+			if containsTime(icsEvent.ExceptionDates, occurrenceStartTime) ||
+				containsTime(overriddenOccurrences[icsEvent.Uid], occurrenceStartTime) {
+				continue
+			}
+			occurrence := icsEvent
+			occurrence.StartTime = occurrenceStartTime
+			if icsEvent.AllDay {
+				// nominal days, independent from DST
+				noDays := daysBetween(icsEvent.StartTime, icsEvent.EndTime)
+				occurrence.EndTime = occurrenceStartTime.AddDate(0, 0, noDays)
+			} else {
+				occurrence.EndTime = occurrenceStartTime.Add(icsEvent.EndTime.Sub(icsEvent.StartTime))
+			}
+			if !occurrence.EndTime.After(tMin) || !occurrence.StartTime.Before(tMax) {
+				continue
+			}
+			occurrence.RecurrenceRule = ""
+			occurrence.ExceptionDates = nil
+			expandedEvents = append(expandedEvents, occurrence)
+		}
+	}
+	return expandedEvents
+}
+
+// parse the value of a RRULE property, e.g. "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH"
+// location is used for UNTIL values without time zone
+func ParseRecurrenceRule(value string, location *time.Location) (RecurrenceRule, error) {
+	recurrenceRule := RecurrenceRule{
+		Interval:  1,
+		WeekStart: time.Monday,
+	}
+	for _, rulePart := range strings.Split(value, ";") {
+		name, partValue, found := strings.Cut(rulePart, "=")
+		if !found {
+			return RecurrenceRule{}, fmt.Errorf("bad RRULE part %q", rulePart)
+		}
+		var err error
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			recurrenceRule.Frequency = strings.ToUpper(partValue)
+		case "INTERVAL":
+			recurrenceRule.Interval, err = strconv.Atoi(partValue)
+			if err == nil && recurrenceRule.Interval < 1 {
+				err = fmt.Errorf("bad INTERVAL %q", partValue)
+			}
+		case "COUNT":
+			recurrenceRule.Count, err = strconv.Atoi(partValue)
+		case "UNTIL":
+			recurrenceRule.Until, err = parseRecurrenceUntil(partValue, location)
+		case "BYDAY":
+			for _, dayValue := range strings.Split(partValue, ",") {
+				recurrenceWeekday, err := parseRecurrenceWeekday(dayValue)
+				if err != nil {
+					return RecurrenceRule{}, err
+				}
+				recurrenceRule.ByDay = append(recurrenceRule.ByDay, recurrenceWeekday)
+			}
+		case "BYMONTHDAY":
+			for _, dayValue := range strings.Split(partValue, ",") {
+				monthDay, err := strconv.Atoi(dayValue)
+				if err != nil || monthDay == 0 || monthDay < -31 || monthDay > 31 {
+					return RecurrenceRule{}, fmt.Errorf("bad BYMONTHDAY %q", dayValue)
+				}
+				recurrenceRule.ByMonthDay = append(recurrenceRule.ByMonthDay, monthDay)
+			}
+		case "BYMONTH":
+			for _, monthValue := range strings.Split(partValue, ",") {
+				month, err := strconv.Atoi(monthValue)
+				if err != nil || month < 1 || month > 12 {
+					return RecurrenceRule{}, fmt.Errorf("bad BYMONTH %q", monthValue)
+				}
+				recurrenceRule.ByMonth = append(recurrenceRule.ByMonth, time.Month(month))
+			}
+		case "WKST":
+			weekStart, found := icsWeekdays[strings.ToUpper(partValue)]
+			if !found {
+				return RecurrenceRule{}, fmt.Errorf("bad WKST %q", partValue)
+			}
+			recurrenceRule.WeekStart = weekStart
+		default:
+			return RecurrenceRule{}, fmt.Errorf("unsupported RRULE part %q", name)
+		}
+		if err != nil {
+			return RecurrenceRule{}, fmt.Errorf("bad RRULE part %q: %w", rulePart, err)
+		}
+	}
+	switch recurrenceRule.Frequency {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	default:
+		return RecurrenceRule{}, fmt.Errorf("unsupported FREQ %q", recurrenceRule.Frequency)
+	}
+	return recurrenceRule, nil
+}
+
+func parseRecurrenceWeekday(value string) (RecurrenceWeekday, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if len(value) < 2 {
+		return RecurrenceWeekday{}, fmt.Errorf("bad BYDAY %q", value)
+	}
+	weekday, found := icsWeekdays[value[len(value)-2:]]
+	if !found {
+		return RecurrenceWeekday{}, fmt.Errorf("bad BYDAY %q", value)
+	}
+	recurrenceWeekday := RecurrenceWeekday{Weekday: weekday}
+	if ordinalValue := value[:len(value)-2]; ordinalValue != "" {
+		ordinal, err := strconv.Atoi(ordinalValue)
+		if err != nil || ordinal == 0 || ordinal < -53 || ordinal > 53 {
+			return RecurrenceWeekday{}, fmt.Errorf("bad BYDAY %q", value)
+		}
+		recurrenceWeekday.Ordinal = ordinal
+	}
+	return recurrenceWeekday, nil
+}
+
+// UNTIL is inclusive: a DATE value includes the whole day
+func parseRecurrenceUntil(value string, location *time.Location) (time.Time, error) {
+	if len(value) == len("20060102") {
+		untilDate, err := time.ParseInLocation("20060102", value, location)
+		return untilDate.AddDate(0, 0, 1).Add(-time.Nanosecond), err
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse("20060102T150405Z", value)
+	}
+	return time.ParseInLocation("20060102T150405", value, location)
+}
+
+// return the start times of the occurrences of the rule starting at or before tMax.
+// DTSTART is always the first occurrence
+func (recurrenceRule RecurrenceRule) Occurrences(dtStart, tMax time.Time) []time.Time {
+	occurrences := []time.Time{dtStart}
+	firstDay := time.Date(dtStart.Year(), dtStart.Month(), dtStart.Day(), 0, 0, 0, 0, time.UTC)
+	for periodIndex := 0; periodIndex < maxRecurrencePeriods; periodIndex++ {
+		periodStart, candidateDays := recurrenceRule.candidateDays(firstDay, periodIndex*recurrenceRule.Interval)
+		if periodStart.After(time.Date(tMax.Year(), tMax.Month(), tMax.Day(), 0, 0, 0, 0, time.UTC)) {
+			break
+		}
+		for _, candidateDay := range candidateDays {
+			candidateTime := time.Date(candidateDay.Year(), candidateDay.Month(), candidateDay.Day(),
+				dtStart.Hour(), dtStart.Minute(), dtStart.Second(), 0, dtStart.Location())
+			if !candidateTime.After(dtStart) {
+				continue
+			}
+			if (recurrenceRule.Count > 0 && len(occurrences) >= recurrenceRule.Count) ||
+				(!recurrenceRule.Until.IsZero() && candidateTime.After(recurrenceRule.Until)) ||
+				candidateTime.After(tMax) {
+				return recurrenceRule.clip(occurrences, tMax)
+			}
+			occurrences = append(occurrences, candidateTime)
+		}
+	}
+	return recurrenceRule.clip(occurrences, tMax)
+}
+
+// drop occurrences after tMax or after UNTIL (DTSTART could be after them)
+func (recurrenceRule RecurrenceRule) clip(occurrences []time.Time, tMax time.Time) []time.Time {
+	clippedOccurrences := []time.Time{}
+	for _, occurrence := range occurrences {
+		if occurrence.After(tMax) || (!recurrenceRule.Until.IsZero() && occurrence.After(recurrenceRule.Until)) {
+			continue
+		}
+		clippedOccurrences = append(clippedOccurrences, occurrence)
+	}
+	return clippedOccurrences
+}
+
+// return the first day of the period at the given offset from the period of firstDay,
+// and the sorted days of that period matching the rule
+func (recurrenceRule RecurrenceRule) candidateDays(firstDay time.Time, periodOffset int) (time.Time, []time.Time) {
+	candidateDays := []time.Time{}
+	switch recurrenceRule.Frequency {
+	case "DAILY":
+		periodStart := firstDay.AddDate(0, 0, periodOffset)
+		if recurrenceRule.matchesMonth(periodStart) && recurrenceRule.matchesMonthDay(periodStart) &&
+			recurrenceRule.matchesWeekday(periodStart) {
+			candidateDays = append(candidateDays, periodStart)
+		}
+		return periodStart, candidateDays
+	case "WEEKLY":
+		daysFromWeekStart := (int(firstDay.Weekday()) - int(recurrenceRule.WeekStart) + 7) % 7
+		periodStart := firstDay.AddDate(0, 0, 7*periodOffset-daysFromWeekStart)
+		for dayIndex := 0; dayIndex < 7; dayIndex++ {
+			currentDay := periodStart.AddDate(0, 0, dayIndex)
+			if len(recurrenceRule.ByDay) == 0 && currentDay.Weekday() != firstDay.Weekday() {
+				continue
+			}
+			if recurrenceRule.matchesMonth(currentDay) && recurrenceRule.matchesWeekday(currentDay) {
+				candidateDays = append(candidateDays, currentDay)
+			}
+		}
+		return periodStart, candidateDays
+	case "MONTHLY":
+		periodStart := time.Date(firstDay.Year(), firstDay.Month()+time.Month(periodOffset), 1, 0, 0, 0, 0, time.UTC)
+		if recurrenceRule.matchesMonth(periodStart) {
+			candidateDays = recurrenceRule.candidateDaysOfMonth(periodStart, firstDay.Day())
+		}
+		return periodStart, candidateDays
+	default:
+		// YEARLY
+		periodStart := time.Date(firstDay.Year()+periodOffset, time.January, 1, 0, 0, 0, 0, time.UTC)
+		if len(recurrenceRule.ByMonth) == 0 && len(recurrenceRule.ByMonthDay) == 0 && len(recurrenceRule.ByDay) > 0 {
+			// week days are relative to the whole year, e.g. 20MO is the 20th Monday of the year
+			periodEnd := periodStart.AddDate(1, 0, 0)
+			return periodStart, recurrenceRule.candidateDaysByWeekday(periodStart, periodEnd)
+		}
+		months := recurrenceRule.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{firstDay.Month()}
+		}
+		for _, month := range slices.Sorted(slices.Values(months)) {
+			monthStart := time.Date(periodStart.Year(), month, 1, 0, 0, 0, 0, time.UTC)
+			candidateDays = append(candidateDays, recurrenceRule.candidateDaysOfMonth(monthStart, firstDay.Day())...)
+		}
+		return periodStart, candidateDays
+	}
+}
+
+// return the days of the month starting at monthStart matching BYMONTHDAY and BYDAY.
+// If none of them is set, defaultDay is used
+func (recurrenceRule RecurrenceRule) candidateDaysOfMonth(monthStart time.Time, defaultDay int) []time.Time {
+	monthEnd := monthStart.AddDate(0, 1, 0)
+	noDaysInMonth := monthEnd.AddDate(0, 0, -1).Day()
+	candidateDays := []time.Time{}
+	switch {
+	case len(recurrenceRule.ByMonthDay) > 0:
+		for dayIndex := 1; dayIndex <= noDaysInMonth; dayIndex++ {
+			currentDay := monthStart.AddDate(0, 0, dayIndex-1)
+			if recurrenceRule.matchesMonthDay(currentDay) &&
+				(len(recurrenceRule.ByDay) == 0 || recurrenceRule.matchesWeekdayInRange(currentDay, monthStart, monthEnd)) {
+				candidateDays = append(candidateDays, currentDay)
+			}
+		}
+	case len(recurrenceRule.ByDay) > 0:
+		candidateDays = recurrenceRule.candidateDaysByWeekday(monthStart, monthEnd)
+	case defaultDay <= noDaysInMonth:
+		// months without that day (e.g. the 31st) are skipped
+		candidateDays = append(candidateDays, monthStart.AddDate(0, 0, defaultDay-1))
+	}
+	return candidateDays
+}
+
+// return the days in [rangeStart, rangeEnd) matching BYDAY, with ordinals relative to the range
+func (recurrenceRule RecurrenceRule) candidateDaysByWeekday(rangeStart, rangeEnd time.Time) []time.Time {
+	candidateDays := []time.Time{}
+	for currentDay := rangeStart; currentDay.Before(rangeEnd); currentDay = currentDay.AddDate(0, 0, 1) {
+		if recurrenceRule.matchesWeekdayInRange(currentDay, rangeStart, rangeEnd) {
+			candidateDays = append(candidateDays, currentDay)
+		}
+	}
+	return candidateDays
+}
+
+func (recurrenceRule RecurrenceRule) matchesWeekdayInRange(currentDay, rangeStart, rangeEnd time.Time) bool {
+	// position of the week day in the range, counting from the start and from the end
+	ordinalFromStart := daysBetween(rangeStart, currentDay)/7 + 1
+	ordinalFromEnd := -(daysBetween(currentDay, rangeEnd)-1)/7 - 1
+	for _, recurrenceWeekday := range recurrenceRule.ByDay {
+		if recurrenceWeekday.Weekday != currentDay.Weekday() {
+			continue
+		}
+		if recurrenceWeekday.Ordinal == 0 || recurrenceWeekday.Ordinal == ordinalFromStart ||
+			recurrenceWeekday.Ordinal == ordinalFromEnd {
+			return true
+		}
+	}
+	return false
+}
+
+func (recurrenceRule RecurrenceRule) matchesWeekday(currentDay time.Time) bool {
+	if len(recurrenceRule.ByDay) == 0 {
+		return true
+	}
+	for _, recurrenceWeekday := range recurrenceRule.ByDay {
+		if recurrenceWeekday.Weekday == currentDay.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func (recurrenceRule RecurrenceRule) matchesMonthDay(currentDay time.Time) bool {
+	if len(recurrenceRule.ByMonthDay) == 0 {
+		return true
+	}
+	noDaysInMonth := time.Date(currentDay.Year(), currentDay.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, monthDay := range recurrenceRule.ByMonthDay {
+		if monthDay == currentDay.Day() || (monthDay < 0 && noDaysInMonth+monthDay+1 == currentDay.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (recurrenceRule RecurrenceRule) matchesMonth(currentDay time.Time) bool {
+	return len(recurrenceRule.ByMonth) == 0 || slices.Contains(recurrenceRule.ByMonth, currentDay.Month())
+}
+
+// number of calendar days between two dates, regardless of DST
+func daysBetween(firstTime, secondTime time.Time) int {
+	firstDay := time.Date(firstTime.Year(), firstTime.Month(), firstTime.Day(), 0, 0, 0, 0, time.UTC)
+	secondDay := time.Date(secondTime.Year(), secondTime.Month(), secondTime.Day(), 0, 0, 0, 0, time.UTC)
+	return int(secondDay.Sub(firstDay).Hours() / 24)
+}
+
+func containsTime(times []time.Time, targetTime time.Time) bool {
+	return slices.ContainsFunc(times, func(currentTime time.Time) bool {
+		return currentTime.Equal(targetTime)
+	})
+}
@@ -0,0 +1,233 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// examples from RFC 5545, section 3.8.5.3
+func TestExpandIcsEvents(t *testing.T) {
+	testCases := []struct {
+		name          string
+		properties    string
+		windowEnd     string
+		expectedDates []string
+	}{
+		{
+			name:       "daily for 10 occurrences",
+			properties: "DTSTART;TZID=America/New_York:19970902T090000\r\nRRULE:FREQ=DAILY;COUNT=10\r\n",
+			windowEnd:  "1998-12-31",
+			expectedDates: []string{"1997-09-02", "1997-09-03", "1997-09-04", "1997-09-05", "1997-09-06",
+				"1997-09-07", "1997-09-08", "1997-09-09", "1997-09-10", "1997-09-11"},
+		},
+		{
+			name:          "every other day",
+			properties:    "DTSTART;TZID=America/New_York:19970902T090000\r\nRRULE:FREQ=DAILY;INTERVAL=2\r\n",
+			windowEnd:     "1997-09-12",
+			expectedDates: []string{"1997-09-02", "1997-09-04", "1997-09-06", "1997-09-08", "1997-09-10"},
+		},
+		{
+			name:          "every 10 days, 5 occurrences",
+			properties:    "DTSTART;TZID=America/New_York:19970902T090000\r\nRRULE:FREQ=DAILY;INTERVAL=10;COUNT=5\r\n",
+			windowEnd:     "1998-12-31",
+			expectedDates: []string{"1997-09-02", "1997-09-12", "1997-09-22", "1997-10-02", "1997-10-12"},
+		},
+		{
+			name:       "weekly for 10 occurrences",
+			properties: "DTSTART;TZID=America/New_York:19970902T090000\r\nRRULE:FREQ=WEEKLY;COUNT=10\r\n",
+			windowEnd:  "1998-12-31",
+			expectedDates: []string{"1997-09-02", "1997-09-09", "1997-09-16", "1997-09-23", "1997-09-30",
+				"1997-10-07", "1997-10-14", "1997-10-21", "1997-10-28", "1997-11-04"},
+		},
+		{
+			name:       "weekly on Tuesday and Thursday for five weeks",
+			properties: "DTSTART;TZID=America/New_York:19970902T090000\r\nRRULE:FREQ=WEEKLY;UNTIL=19971007T000000Z;WKST=SU;BYDAY=TU,TH\r\n",
+			windowEnd:  "1998-12-31",
+			expectedDates: []string{"1997-09-02", "1997-09-04", "1997-09-09", "1997-09-11", "1997-09-16",
+				"1997-09-18", "1997-09-23", "1997-09-25", "1997-09-30", "1997-10-02"},
+		},
+		{
+			name:       "every other week on Monday, Wednesday and Friday until December 24, 1997",
+			properties: "DTSTART;TZID=America/New_York:19970901T090000\r\nRRULE:FREQ=WEEKLY;INTERVAL=2;UNTIL=19971224T000000Z;WKST=SU;BYDAY=MO,WE,FR\r\n",
+			windowEnd:  "1998-12-31",
+			expectedDates: []string{"1997-09-01", "1997-09-03", "1997-09-05", "1997-09-15", "1997-09-17",
+				"1997-09-19", "1997-09-29", "1997-10-01", "1997-10-03", "1997-10-13", "1997-10-15",
+				"1997-10-17", "1997-10-27", "1997-10-29", "1997-10-31", "1997-11-10", "1997-11-12",
+				"1997-11-14", "1997-11-24", "1997-11-26", "1997-11-28", "1997-12-08", "1997-12-10",
+				"1997-12-12", "1997-12-22"},
+		},
+		{
+			name:       "every other week on Tuesday and Thursday, for 8 occurrences",
+			properties: "DTSTART;TZID=America/New_York:19970902T090000\r\nRRULE:FREQ=WEEKLY;INTERVAL=2;COUNT=8;WKST=SU;BYDAY=TU,TH\r\n",
+			windowEnd:  "1998-12-31",
+			expectedDates: []string{"1997-09-02", "1997-09-04", "1997-09-16", "1997-09-18", "1997-09-30",
+				"1997-10-02", "1997-10-14", "1997-10-16"},
+		},
+		{
+			name:       "monthly on the first Friday for 10 occurrences",
+			properties: "DTSTART;TZID=America/New_York:19970905T090000\r\nRRULE:FREQ=MONTHLY;COUNT=10;BYDAY=1FR\r\n",
+			windowEnd:  "1998-12-31",
+			expectedDates: []string{"1997-09-05", "1997-10-03", "1997-11-07", "1997-12-05", "1998-01-02",
+				"1998-02-06", "1998-03-06", "1998-04-03", "1998-05-01", "1998-06-05"},
+		},
+		{
+			name:       "every other month on the first and last Sunday of the month for 10 occurrences",
+			properties: "DTSTART;TZID=America/New_York:19970907T090000\r\nRRULE:FREQ=MONTHLY;INTERVAL=2;COUNT=10;BYDAY=1SU,-1SU\r\n",
+			windowEnd:  "1998-12-31",
+			expectedDates: []string{"1997-09-07", "1997-09-28", "1997-11-02", "1997-11-30", "1998-01-04",
+				"1998-01-25", "1998-03-01", "1998-03-29", "1998-05-03", "1998-05-31"},
+		},
+		{
+			name:          "monthly on the third-to-the-last day of the month, for 6 occurrences",
+			properties:    "DTSTART;TZID=America/New_York:19970928T090000\r\nRRULE:FREQ=MONTHLY;BYMONTHDAY=-3;COUNT=6\r\n",
+			windowEnd:     "1998-12-31",
+			expectedDates: []string{"1997-09-28", "1997-10-29", "1997-11-28", "1997-12-29", "1998-01-29", "1998-02-26"},
+		},
+		{
+			name:       "monthly on the 2nd and 15th of the month for 10 occurrences",
+			properties: "DTSTART;TZID=America/New_York:19970902T090000\r\nRRULE:FREQ=MONTHLY;COUNT=10;BYMONTHDAY=2,15\r\n",
+			windowEnd:  "1998-12-31",
+			expectedDates: []string{"1997-09-02", "1997-09-15", "1997-10-02", "1997-10-15", "1997-11-02",
+				"1997-11-15", "1997-12-02", "1997-12-15", "1998-01-02", "1998-01-15"},
+		},
+		{
+			name:       "yearly in June and July for 10 occurrences",
+			properties: "DTSTART;TZID=America/New_York:19970610T090000\r\nRRULE:FREQ=YEARLY;COUNT=10;BYMONTH=6,7\r\n",
+			windowEnd:  "2005-12-31",
+			expectedDates: []string{"1997-06-10", "1997-07-10", "1998-06-10", "1998-07-10", "1999-06-10",
+				"1999-07-10", "2000-06-10", "2000-07-10", "2001-06-10", "2001-07-10"},
+		},
+		{
+			name:          "every 20th Monday of the year",
+			properties:    "DTSTART;TZID=America/New_York:19970519T090000\r\nRRULE:FREQ=YEARLY;BYDAY=20MO\r\n",
+			windowEnd:     "1999-12-31",
+			expectedDates: []string{"1997-05-19", "1998-05-18", "1999-05-17"},
+		},
+		{
+			name:       "every Thursday in March",
+			properties: "DTSTART;TZID=America/New_York:19970313T090000\r\nRRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=TH\r\n",
+			windowEnd:  "1999-12-31",
+			expectedDates: []string{"1997-03-13", "1997-03-20", "1997-03-27", "1998-03-05", "1998-03-12",
+				"1998-03-19", "1998-03-26", "1999-03-04", "1999-03-11", "1999-03-18", "1999-03-25"},
+		},
+		{
+			name:          "every Friday the 13th, excluding DTSTART",
+			properties:    "DTSTART;TZID=America/New_York:19970902T090000\r\nEXDATE;TZID=America/New_York:19970902T090000\r\nRRULE:FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13\r\n",
+			windowEnd:     "2000-12-31",
+			expectedDates: []string{"1998-02-13", "1998-03-13", "1998-11-13", "1999-08-13", "2000-10-13"},
+		},
+	}
+	newYork, _ := time.LoadLocation("America/New_York")
+	windowStart := time.Date(1997, time.January, 1, 0, 0, 0, 0, newYork)
+	for _, testCase := range testCases {
+		icsEvents, err := ParseIcs(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:rfc\r\n" +
+			testCase.properties + "DURATION:PT1H\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"))
+		if err != nil {
+			t.Errorf("%s: error while parsing: %v", testCase.name, err)
+			continue
+		}
+		windowEnd, _ := time.ParseInLocation(time.DateOnly, testCase.windowEnd, newYork)
+		expandedEvents := ExpandIcsEvents(icsEvents, windowStart, windowEnd)
+		resultingDates := []string{}
+		for _, expandedEvent := range expandedEvents {
+			resultingDates = append(resultingDates, expandedEvent.StartTime.Format(time.DateOnly))
+			if expandedEvent.StartTime.Hour() != 9 || expandedEvent.EndTime.Sub(expandedEvent.StartTime) != time.Hour {
+				t.Errorf("%s: bad occurrence %v - %v", testCase.name, expandedEvent.StartTime, expandedEvent.EndTime)
+			}
+		}
+		if strings.Join(resultingDates, " ") != strings.Join(testCase.expectedDates, " ") {
+			t.Errorf("%s: expected %v, got %v", testCase.name, testCase.expectedDates, resultingDates)
+		}
+	}
+}
+
+func TestExpandIcsEventsWithOverrides(t *testing.T) {
+	icsCalendar := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:standup\r\n" +
+		"DTSTART;TZID=Europe/Rome:20251208T093000\r\n" +
+		"DTEND;TZID=Europe/Rome:20251208T094500\r\n" +
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR\r\n" +
+		"EXDATE;TZID=Europe/Rome:20251209T093000,20251210T093000\r\n" +
+		"SUMMARY:Standup\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:standup\r\n" +
+		"RECURRENCE-ID;TZID=Europe/Rome:20251211T093000\r\n" +
+		"DTSTART;TZID=Europe/Rome:20251211T113000\r\n" +
+		"DTEND;TZID=Europe/Rome:20251211T120000\r\n" +
+		"SUMMARY:Standup (moved)\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	icsEvents, err := ParseIcs(strings.NewReader(icsCalendar))
+	if err != nil {
+		t.Errorf("Error while parsing: %v", err)
+		return
+	}
+	rome, _ := time.LoadLocation("Europe/Rome")
+	// window starts after the first occurrence and stops before the second week ends
+	windowStart := time.Date(2025, time.December, 9, 0, 0, 0, 0, rome)
+	windowEnd := time.Date(2025, time.December, 16, 0, 0, 0, 0, rome)
+	expandedEvents := ExpandIcsEvents(icsEvents, windowStart, windowEnd)
+	calendarEvents := ConvertIcsEventsToCalendarEvents(expandedEvents, windowStart, windowEnd, "test.ics")
+	expectedStartTimes := []string{"2025-12-11 11:30", "2025-12-12 09:30", "2025-12-15 09:30"}
+	expectedDurations := []int{30, 15, 15}
+	if len(calendarEvents) != len(expectedStartTimes) {
+		t.Errorf("Length mismatch about no. events: %v", len(calendarEvents))
+		PrintEventList(calendarEvents)
+		return
+	}
+	for eventIndex, calendarEvent := range calendarEvents {
+		if calendarEvent.StartTime.Format("2006-01-02 15:04") != expectedStartTimes[eventIndex] ||
+			calendarEvent.Duration != expectedDurations[eventIndex] {
+			t.Errorf("Error while expanding: mismatching event index %v", eventIndex)
+			PrintEventList(calendarEvents)
+			return
+		}
+	}
+}
+
+func TestExpandIcsEventsWithUnsupportedRule(t *testing.T) {
+	var warnings strings.Builder
+	defaultWarningOutput := warningOutput
+	defer func() { warningOutput = defaultWarningOutput }()
+	warningOutput = &warnings
+
+	// Outlook writes "last weekday of the month" with BYSETPOS
+	icsCalendar := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nUID:daily\r\nSUMMARY:Standup\r\nDTSTART;TZID=Europe/Rome:20251201T093000\r\nDURATION:PT15M\r\nRRULE:FREQ=DAILY;COUNT=3\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:monthly\r\nSUMMARY:Review\r\nDTSTART;TZID=Europe/Rome:20251201T150000\r\nDURATION:PT1H\r\n" +
+		"RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:single\r\nSUMMARY:Lunch\r\nDTSTART;TZID=Europe/Rome:20251202T123000\r\nDURATION:PT1H\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	icsEvents, err := ParseIcs(strings.NewReader(icsCalendar))
+	if err != nil {
+		t.Errorf("Error while parsing: %v", err)
+		return
+	}
+	rome, _ := time.LoadLocation("Europe/Rome")
+	expandedEvents := ExpandIcsEvents(icsEvents, time.Date(2025, time.December, 1, 0, 0, 0, 0, rome), time.Date(2026, time.January, 1, 0, 0, 0, 0, rome))
+	expectedEvents := []string{"daily 2025-12-01", "daily 2025-12-02", "daily 2025-12-03", "monthly 2025-12-01", "single 2025-12-02"}
+	resultingEvents := []string{}
+	for _, expandedEvent := range expandedEvents {
+		resultingEvents = append(resultingEvents, expandedEvent.Uid+" "+expandedEvent.StartTime.Format(time.DateOnly))
+	}
+	if strings.Join(resultingEvents, ", ") != strings.Join(expectedEvents, ", ") {
+		t.Errorf("Expected %v, got %v", expectedEvents, resultingEvents)
+	}
+	if !strings.Contains(warnings.String(), `event monthly "Review": unsupported RRULE part "BYSETPOS"`) {
+		t.Errorf("Unexpected warnings: %q", warnings.String())
+	}
+
+	// the first occurrence is dropped if outside the range, non-recurring events are returned as they are.
+	// Each expansion warns again
+	warnings.Reset()
+	expandedEvents = ExpandIcsEvents(icsEvents, time.Date(2026, time.January, 1, 0, 0, 0, 0, rome), time.Date(2026, time.February, 1, 0, 0, 0, 0, rome))
+	if len(expandedEvents) != 1 || expandedEvents[0].Uid != "single" {
+		t.Errorf("Unexpected events: %v", expandedEvents)
+	}
+	if !strings.Contains(warnings.String(), `unsupported RRULE part "BYSETPOS"`) {
+		t.Errorf("Warning not repeated: %q", warnings.String())
+	}
+}
@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	icsDateFormat        = "20060102"
+	icsDateTimeFormat    = "20060102T150405"
+	icsUtcDateTimeFormat = "20060102T150405Z"
+	icsMaxLineLength     = 75
+)
+
+func init() {
+	RegisterRenderer("ics", IcsRenderer{})
+}
+
+// iCalendar (RFC 5545) feed: a VFREEBUSY component with the free slots, or a VEVENT per event
+// when all events are shown, transparent unless it makes the user busy. Timestamp is used as DTSTAMP,
+// the current time when not set
+type IcsRenderer struct {
+	Timestamp time.Time
+}
+
+func (icsRenderer IcsRenderer) Render(w io.Writer, dailyAgendas []DailyAgenda, renderOptions RenderOptions) error {
+	timestamp := icsRenderer.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	icsWriter := icsContentWriter{w: w}
+	icsWriter.writeLine("BEGIN:VCALENDAR")
+	icsWriter.writeLine("VERSION:2.0")
+	icsWriter.writeLine("PRODID:-//freeslots//freeslots//EN")
+	icsWriter.writeLine("METHOD:PUBLISH")
+	if renderOptions.ShowAllEvents {
+		writeIcsEvents(&icsWriter, dailyAgendas, timestamp, renderOptions.IsBusy)
+	} else {
+		writeIcsFreeBusy(&icsWriter, dailyAgendas, timestamp)
+	}
+	icsWriter.writeLine("END:VCALENDAR")
+	return icsWriter.err
+}
+
+// a VTIMEZONE per named location of timed events, then a VEVENT per event
+func writeIcsEvents(icsWriter *icsContentWriter, dailyAgendas []DailyAgenda, timestamp time.Time, isBusy func(CalendarEvent) bool) {
+	locations := []*time.Location{}
+	firstTimes := map[*time.Location]time.Time{}
+	lastTimes := map[*time.Location]time.Time{}
+	for _, dailyAgenda := range dailyAgendas {
+		for _, event := range dailyAgenda.Events {
+			location := event.StartTime.Location()
+			if event.AllDay || !hasIcsTzid(location) {
+				continue
+			}
+			if _, found := firstTimes[location]; !found {
+				locations = append(locations, location)
+				firstTimes[location] = event.StartTime
+			}
+			if event.StartTime.Before(firstTimes[location]) {
+				firstTimes[location] = event.StartTime
+			}
+			if event.GetEndTime().After(lastTimes[location]) {
+				lastTimes[location] = event.GetEndTime()
+			}
+		}
+	}
+	for _, location := range locations {
+		writeIcsTimezone(icsWriter, location, firstTimes[location], lastTimes[location])
+	}
+	for _, dailyAgenda := range dailyAgendas {
+		for index, event := range dailyAgenda.Events {
+			icsWriter.writeLine("BEGIN:VEVENT")
+			icsWriter.writeLine(fmt.Sprintf("UID:%s-%d@freeslots", event.StartTime.UTC().Format(icsUtcDateTimeFormat), index))
+			icsWriter.writeLine("DTSTAMP:" + timestamp.UTC().Format(icsUtcDateTimeFormat))
+			if event.AllDay {
+				icsWriter.writeLine("DTSTART;VALUE=DATE:" + event.StartTime.Format(icsDateFormat))
+				icsWriter.writeLine("DTEND;VALUE=DATE:" + event.GetEndTime().Format(icsDateFormat))
+			} else {
+				icsWriter.writeLine("DTSTART" + formatIcsDateTime(event.StartTime))
+				icsWriter.writeLine("DTEND" + formatIcsDateTime(event.GetEndTime()))
+			}
+			icsWriter.writeLine("SUMMARY:" + escapeIcsText(event.Description))
+			// importing the feed must not block time the user is free in
+			if isBusy == nil || isBusy(event) {
+				icsWriter.writeLine("TRANSP:OPAQUE")
+			} else {
+				icsWriter.writeLine("TRANSP:TRANSPARENT")
+			}
+			icsWriter.writeLine("END:VEVENT")
+		}
+	}
+}
+
+// a single VFREEBUSY covering all days, with a FREEBUSY property per free slot
+func writeIcsFreeBusy(icsWriter *icsContentWriter, dailyAgendas []DailyAgenda, timestamp time.Time) {
+	icsWriter.writeLine("BEGIN:VFREEBUSY")
+	icsWriter.writeLine("UID:" + timestamp.UTC().Format(icsUtcDateTimeFormat) + "-freebusy@freeslots")
+	icsWriter.writeLine("DTSTAMP:" + timestamp.UTC().Format(icsUtcDateTimeFormat))
+	if len(dailyAgendas) > 0 {
+		firstDay := GetPureDate(dailyAgendas[0].Date)
+		lastDay := GetPureDateAfterDays(dailyAgendas[len(dailyAgendas)-1].Date, 1)
+		icsWriter.writeLine("DTSTART:" + firstDay.UTC().Format(icsUtcDateTimeFormat))
+		icsWriter.writeLine("DTEND:" + lastDay.UTC().Format(icsUtcDateTimeFormat))
+	}
+	for _, dailyAgenda := range dailyAgendas {
+		for _, event := range dailyAgenda.Events {
+			icsWriter.writeLine("FREEBUSY;FBTYPE=FREE:" + event.StartTime.UTC().Format(icsUtcDateTimeFormat) + "/" +
+				event.GetEndTime().UTC().Format(icsUtcDateTimeFormat))
+		}
+	}
+	icsWriter.writeLine("END:VFREEBUSY")
+}
+
+// VTIMEZONE with an observance per offset in effect between firstTime and lastTime
+func writeIcsTimezone(icsWriter *icsContentWriter, location *time.Location, firstTime, lastTime time.Time) {
+	icsWriter.writeLine("BEGIN:VTIMEZONE")
+	icsWriter.writeLine("TZID:" + location.String())
+	zoneTime := firstTime.In(location)
+	zoneStart, zoneEnd := zoneTime.ZoneBounds()
+	_, offsetFrom := zoneTime.Zone()
+	if !zoneStart.IsZero() {
+		_, offsetFrom = zoneStart.Add(-time.Second).Zone()
+	}
+	for {
+		zoneName, offsetTo := zoneTime.Zone()
+		observance := "STANDARD"
+		if zoneTime.IsDST() {
+			observance = "DAYLIGHT"
+		}
+		// DTSTART of an observance is in the local time before the transition
+		observanceStart := "19700101T000000"
+		if !zoneStart.IsZero() {
+			observanceStart = zoneStart.UTC().Add(time.Duration(offsetFrom) * time.Second).Format(icsDateTimeFormat)
+		}
+		icsWriter.writeLine("BEGIN:" + observance)
+		icsWriter.writeLine("DTSTART:" + observanceStart)
+		icsWriter.writeLine("TZOFFSETFROM:" + formatIcsUtcOffset(offsetFrom))
+		icsWriter.writeLine("TZOFFSETTO:" + formatIcsUtcOffset(offsetTo))
+		icsWriter.writeLine("TZNAME:" + escapeIcsText(zoneName))
+		icsWriter.writeLine("END:" + observance)
+		if zoneEnd.IsZero() || zoneEnd.After(lastTime) {
+			break
+		}
+		offsetFrom = offsetTo
+		zoneTime = zoneEnd.In(location)
+		zoneStart, zoneEnd = zoneTime.ZoneBounds()
+	}
+	icsWriter.writeLine("END:VTIMEZONE")
+}
+
+// locations without an IANA name are written in UTC
+func hasIcsTzid(location *time.Location) bool {
+	return location != time.UTC && location != time.Local && location.String() != "UTC" && location.String() != "Local"
+}
+
+// value of a DATE-TIME property, including the leading ":" or TZID parameter
+func formatIcsDateTime(t time.Time) string {
+	if !hasIcsTzid(t.Location()) {
+		return ":" + t.UTC().Format(icsUtcDateTimeFormat)
+	}
+	return ";TZID=" + t.Location().String() + ":" + t.Format(icsDateTimeFormat)
+}
+
+// UTC offset in the form +HHMM
+func formatIcsUtcOffset(offsetSeconds int) string {
+	sign := "+"
+	if offsetSeconds < 0 {
+		sign = "-"
+		offsetSeconds = -offsetSeconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, offsetSeconds/3600, offsetSeconds%3600/60)
+}
+
+func escapeIcsText(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, "\r\n", `\n`, "\n", `\n`)
+	return replacer.Replace(value)
+}
+
+// writer of content lines, folded at 75 octets and terminated by CRLF.
+// The first error is kept and further writes are skipped
+type icsContentWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (icsWriter *icsContentWriter) writeLine(line string) {
+	if icsWriter.err != nil {
+		return
+	}
+	_, icsWriter.err = io.WriteString(icsWriter.w, foldIcsLine(line)+"\r\n")
+}
+
+// split a content line in lines of at most 75 octets, without breaking UTF-8 sequences
+func foldIcsLine(line string) string {
+	var folded strings.Builder
+	lineLength := 0
+	for _, character := range line {
+		characterLength := utf8.RuneLen(character)
+		if lineLength+characterLength > icsMaxLineLength {
+			folded.WriteString("\r\n ")
+			lineLength = 1
+		}
+		folded.WriteRune(character)
+		lineLength += characterLength
+	}
+	return folded.String()
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIcsRendererEvents(t *testing.T) {
+	location, _ := time.LoadLocation("Europe/Rome")
+	firstDay := time.Date(2025, 3, 28, 0, 0, 0, 0, location)
+	secondDay := time.Date(2025, 3, 31, 0, 0, 0, 0, location)
+	longDescription := strings.Repeat("Riunione di più persone; con ordine, del giorno\n", 3)
+	dailyAgendas := []DailyAgenda{
+		{Date: firstDay, Events: []CalendarEvent{
+			{StartTime: firstDay.Add(9 * time.Hour), Duration: 60, Description: "Stand-up"},
+		}},
+		{Date: secondDay, Events: []CalendarEvent{
+			{StartTime: secondDay.Add(14 * time.Hour), Duration: 90, Description: longDescription},
+		}},
+	}
+	var output bytes.Buffer
+	icsRenderer := IcsRenderer{Timestamp: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)}
+	if err := icsRenderer.Render(&output, dailyAgendas, RenderOptions{ShowAllEvents: true, ShowDescription: true}); err != nil {
+		t.Errorf("Error while rendering ics: %v", err)
+		return
+	}
+	expectedLines := []string{
+		"BEGIN:VCALENDAR\r\n",
+		"TZID:Europe/Rome\r\n",
+		"BEGIN:STANDARD\r\nDTSTART:20241027T030000\r\nTZOFFSETFROM:+0200\r\nTZOFFSETTO:+0100\r\nTZNAME:CET\r\nEND:STANDARD\r\n",
+		"BEGIN:DAYLIGHT\r\nDTSTART:20250330T020000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\nTZNAME:CEST\r\nEND:DAYLIGHT\r\n",
+		"DTSTAMP:20250301T120000Z\r\n",
+		"DTSTART;TZID=Europe/Rome:20250328T090000\r\n",
+		"DTEND;TZID=Europe/Rome:20250331T153000\r\n",
+		"SUMMARY:Riunione di più persone\\; con ordine\\, del giorno\\nRiunione di pi\r\n ",
+	}
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(output.String(), expectedLine) {
+			t.Errorf("Error while rendering ics: %q not found in %q", expectedLine, output.String())
+		}
+	}
+	for _, line := range strings.Split(output.String(), "\r\n") {
+		if len(line) > icsMaxLineLength {
+			t.Errorf("Line longer than %v octets: %q", icsMaxLineLength, line)
+		}
+	}
+
+	icsEvents, err := ParseIcs(&output)
+	if err != nil {
+		t.Errorf("Error while parsing rendered ics: %v", err)
+		return
+	}
+	if len(icsEvents) != 2 {
+		t.Errorf("Unexpected number of events: %v", len(icsEvents))
+		return
+	}
+	for index, icsEvent := range icsEvents {
+		event := dailyAgendas[index].Events[0]
+		if !icsEvent.StartTime.Equal(event.StartTime) || !icsEvent.EndTime.Equal(event.GetEndTime()) || icsEvent.Summary != event.Description {
+			t.Errorf("Unexpected event after round trip: %+v", icsEvent)
+		}
+	}
+}
+
+func TestIcsRendererFreeBusy(t *testing.T) {
+	dailyAgenda, _ := ParseDailyAgenda("d2025-12-10,m30,s16,aXX--YY")
+	var output bytes.Buffer
+	icsRenderer := IcsRenderer{Timestamp: time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)}
+	if err := icsRenderer.Render(&output, []DailyAgenda{dailyAgenda}, RenderOptions{}); err != nil {
+		t.Errorf("Error while rendering ics: %v", err)
+		return
+	}
+	firstSlot := dailyAgenda.Events[0]
+	expectedLines := []string{
+		"BEGIN:VFREEBUSY\r\n",
+		"DTSTART:" + GetPureDate(dailyAgenda.Date).UTC().Format(icsUtcDateTimeFormat) + "\r\n",
+		"FREEBUSY;FBTYPE=FREE:" + firstSlot.StartTime.UTC().Format(icsUtcDateTimeFormat) + "/" +
+			firstSlot.GetEndTime().UTC().Format(icsUtcDateTimeFormat) + "\r\n",
+		"END:VFREEBUSY\r\nEND:VCALENDAR\r\n",
+	}
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(output.String(), expectedLine) {
+			t.Errorf("Error while rendering ics: %q not found in %q", expectedLine, output.String())
+		}
+	}
+	if strings.Count(output.String(), "FREEBUSY;FBTYPE=FREE:") != 2 {
+		t.Errorf("Unexpected number of free slots in %q", output.String())
+	}
+}
+
+func TestIcsRendererAllDayAndFreeEvents(t *testing.T) {
+	location, _ := time.LoadLocation("Europe/Rome")
+	day := time.Date(2025, 12, 10, 0, 0, 0, 0, location)
+	dailyAgenda := DailyAgenda{Date: day, Events: []CalendarEvent{
+		{StartTime: day, Duration: 24 * 60, Description: "Conference", AllDay: true},
+		{StartTime: day.Add(9 * time.Hour), Duration: 60, Description: "Meeting"},
+		{StartTime: day.Add(11 * time.Hour), Duration: 60, Description: "Free", Transparency: TransparencyTransparent},
+	}}
+	var output bytes.Buffer
+	freeSlotsCoreAlgorithm := FreeSlotsCoreAlgorithm{
+		ShowAllEvents: true,
+		Format:        "ics",
+		Location:      location,
+		Output:        &output,
+	}
+	if err := freeSlotsCoreAlgorithm.FreeSlotsCore([]DailyAgenda{dailyAgenda}); err != nil {
+		t.Errorf("Error while rendering ics: %v", err)
+		return
+	}
+	// all-day events are dates, and only the meeting makes the user busy
+	expectedLines := []string{
+		"DTSTART;VALUE=DATE:20251210\r\nDTEND;VALUE=DATE:20251211\r\nSUMMARY:Conference\r\nTRANSP:TRANSPARENT\r\n",
+		"SUMMARY:Meeting\r\nTRANSP:OPAQUE\r\n",
+		"SUMMARY:Free\r\nTRANSP:TRANSPARENT\r\n",
+	}
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(output.String(), expectedLine) {
+			t.Errorf("Error while rendering ics: %q not found in %q", expectedLine, output.String())
+		}
+	}
+
+	// dates are floating, read in the time zone they were written in
+	icsEvents, err := ParseIcsInLocation(&output, location)
+	if err != nil || len(icsEvents) != 3 || !icsEvents[0].AllDay || !icsEvents[0].StartTime.Equal(day) ||
+		!icsEvents[0].EndTime.Equal(GetPureDateAfterDays(day, 1)) {
+		t.Errorf("Unexpected events after round trip: %+v %v", icsEvents, err)
+	}
+
+	output.Reset()
+	freeSlotsCoreAlgorithm.AllDayBusy = true
+	if err := freeSlotsCoreAlgorithm.FreeSlotsCore([]DailyAgenda{dailyAgenda}); err != nil ||
+		!strings.Contains(output.String(), "SUMMARY:Conference\r\nTRANSP:OPAQUE\r\n") {
+		t.Errorf("All-day event not busy: %q %v", output.String(), err)
+	}
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// version of the JSON schema, to be increased on every incompatible change
+const JsonSchemaVersion = 1
+
+// JSON document, see the "JSON output" section of the README
+type jsonDocument struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	Kind          string    `json:"kind"`
+	Days          []jsonDay `json:"days"`
+}
+
+type jsonDay struct {
+	Date     string     `json:"date"`
+	Timezone string     `json:"timezone"`
+	Slots    []jsonSlot `json:"slots"`
+}
+
+type jsonSlot struct {
+	Start           string `json:"start"`
+	End             string `json:"end"`
+	DurationMinutes int    `json:"durationMinutes"`
+	AllDay          bool   `json:"allDay"`
+	Description     string `json:"description,omitempty"`
+}
+
+func init() {
+	RegisterRenderer("json", JsonRenderer{})
+}
+
+// single JSON document with a day per agenda and a slot per event or free slot
+type JsonRenderer struct{}
+
+func (jsonRenderer JsonRenderer) Render(w io.Writer, dailyAgendas []DailyAgenda, renderOptions RenderOptions) error {
+	document := jsonDocument{
+		SchemaVersion: JsonSchemaVersion,
+		Kind:          "freeSlots",
+		Days:          []jsonDay{},
+	}
+	if renderOptions.ShowAllEvents {
+		document.Kind = "events"
+	}
+	for _, dailyAgenda := range dailyAgendas {
+		day := jsonDay{
+			Date:     dailyAgenda.Date.Format(time.DateOnly),
+			Timezone: getTimezoneName(dailyAgenda.Date),
+			Slots:    []jsonSlot{},
+		}
+		for _, event := range dailyAgenda.Events {
+			slot := jsonSlot{
+				Start:           event.StartTime.Format(time.RFC3339),
+				End:             event.GetEndTime().Format(time.RFC3339),
+				DurationMinutes: event.Duration,
+				AllDay:          event.AllDay,
+			}
+			if renderOptions.ShowDescription {
+				slot.Description = event.Description
+			}
+			day.Slots = append(day.Slots, slot)
+		}
+		document.Days = append(document.Days, day)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+// IANA name of the location of the given time, or its UTC offset (e.g. "+01:00") when the name of
+// the local time zone is not known
+func getTimezoneName(t time.Time) string {
+	name := t.Location().String()
+	if name != "Local" {
+		return name
+	}
+	if localName := getLocalTimezoneName(); localName != "" {
+		return localName
+	}
+	return t.Format("-07:00")
+}
+
+// IANA name of the local time zone, from $TZ like the time package, or from the /etc/localtime link.
+// Empty when neither tells a known name
+func getLocalTimezoneName() string {
+	name, found := os.LookupEnv("TZ")
+	switch {
+	case found && name == "":
+		return "UTC"
+	case found:
+		name = strings.TrimPrefix(name, ":")
+	default:
+		if link, err := os.Readlink("/etc/localtime"); err == nil {
+			_, name, _ = strings.Cut(link, "zoneinfo/")
+		}
+	}
+	if name == "" || filepath.IsAbs(name) {
+		return ""
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,181 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// name of the file with the settings of a profile, in the directory of the profile
+const profileFileName = "profile.json"
+
+// endpoint revoking Google OAuth tokens
+var tokenRevokeURL = "https://oauth2.googleapis.com/revoke"
+
+// returned by LoadProfile when the directory of the profile doesn't exist
+var ErrUnknownProfile = errors.New("unknown profile")
+
+var profileNameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// a named account with its own credentials, token and defaults, stored in a directory of the config
+// directory. Empty settings keep the defaults of the command line, relative paths are in that directory
+type Profile struct {
+	Name           string   `json:"-"`
+	Dir            string   `json:"-"`
+	UserEmail      string   `json:"useremail,omitempty"`
+	Credentials    string   `json:"creds,omitempty"`
+	Token          string   `json:"token,omitempty"`
+	AuthMode       string   `json:"authmode,omitempty"`
+	ServiceAccount string   `json:"serviceaccount,omitempty"`
+	Subject        string   `json:"subject,omitempty"`
+	Calendars      []string `json:"calendars,omitempty"`
+	WorkingHours   string   `json:"hours,omitempty"`
+	Format         string   `json:"format,omitempty"`
+	Timezone       string   `json:"timezone,omitempty"`
+}
+
+// directory of the profiles: $XDG_CONFIG_HOME/freeslots, or the config directory of the platform
+func GetProfilesDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "freeslots"), nil
+}
+
+// LoadProfile reads a profile from its directory, which must exist
+func LoadProfile(profilesDir string, name string) (Profile, error) {
+	profile, err := newProfile(profilesDir, name)
+	if err != nil {
+		return profile, err
+	}
+	if _, err := os.Stat(profile.Dir); errors.Is(err, fs.ErrNotExist) {
+		return profile, fmt.Errorf("%w %q, log in to create it", ErrUnknownProfile, name)
+	}
+	profileFile, err := os.ReadFile(filepath.Join(profile.Dir, profileFileName))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return profile, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(profileFile, &profile); err != nil {
+			return profile, fmt.Errorf("bad profile %q: %w", name, err)
+		}
+	}
+	return profile, nil
+}
+
+func newProfile(profilesDir string, name string) (Profile, error) {
+	if !profileNameRegexp.MatchString(name) {
+		return Profile{}, fmt.Errorf("bad profile name %q, use letters, digits, '.', '_' and '-'", name)
+	}
+	return Profile{Name: name, Dir: filepath.Join(profilesDir, name)}, nil
+}
+
+// SaveProfile writes the settings of a profile, creating its directory
+func SaveProfile(profile Profile) error {
+	if err := os.MkdirAll(profile.Dir, 0700); err != nil {
+		return err
+	}
+	profileFile, err := json.MarshalIndent(profile, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(profile.Dir, profileFileName), append(profileFile, '\n'), 0600)
+}
+
+// ListProfiles returns the profiles in the profiles directory, sorted by name
+func ListProfiles(profilesDir string) ([]Profile, error) {
+	entries, err := os.ReadDir(profilesDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Profile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	profiles := []Profile{}
+	for _, entry := range entries {
+		if !entry.IsDir() || !profileNameRegexp.MatchString(entry.Name()) {
+			continue
+		}
+		profile, err := LoadProfile(profilesDir, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+// path of a file of the profile, credentials.json and token.json in its directory by default
+func (profile Profile) GetPath(fileName string, defaultFileName string) string {
+	if fileName == "" {
+		fileName = defaultFileName
+	}
+	if filepath.IsAbs(fileName) {
+		return fileName
+	}
+	return filepath.Join(profile.Dir, fileName)
+}
+
+func (profile Profile) GetCredentialsFileName() string {
+	return profile.GetPath(profile.Credentials, "credentials.json")
+}
+
+func (profile Profile) GetTokenFileName() string {
+	return profile.GetPath(profile.Token, "token.json")
+}
+
+func (profile Profile) GetServiceAccountFileName() string {
+	if profile.ServiceAccount == "" {
+		return ""
+	}
+	return profile.GetPath(profile.ServiceAccount, "")
+}
+
+// tell if the profile can access Google Calendar without signing in, with the key of its service
+// account or with its token
+func (profile Profile) IsLoggedIn() bool {
+	fileName := profile.GetTokenFileName()
+	if profile.ServiceAccount != "" {
+		fileName = profile.GetServiceAccountFileName()
+	}
+	_, err := os.Stat(fileName)
+	return err == nil
+}
+
+// Login makes sure a valid token is saved, signing in if needed
+func Login(calendarExporterStatus CalendarExporterStatus) error {
+	_, err := CreateCalendarService(calendarExporterStatus)
+	return err
+}
+
+// Logout revokes the saved token at Google and deletes its file. A token already revoked or
+// expired is deleted anyway
+func Logout(tokenFileName string) error {
+	tok, err := tokenFromFile(tokenFileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("not logged in, %v not found", tokenFileName)
+	}
+	if err == nil {
+		revokedToken := tok.RefreshToken
+		if revokedToken == "" {
+			revokedToken = tok.AccessToken
+		}
+		response, err := defaultHttpClient.PostForm(tokenRevokeURL, url.Values{"token": {revokedToken}})
+		if err != nil {
+			return fmt.Errorf("unable to revoke token: %w", err)
+		}
+		response.Body.Close()
+		// Google answers 400 for tokens that are no longer valid
+		if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusBadRequest {
+			return fmt.Errorf("unable to revoke token: %v", response.Status)
+		}
+	}
+	return os.Remove(tokenFileName)
+}
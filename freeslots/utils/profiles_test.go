@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestProfiles(t *testing.T) {
+	profilesDir := t.TempDir()
+	profiles, err := ListProfiles(filepath.Join(profilesDir, "missing"))
+	if err != nil || len(profiles) != 0 {
+		t.Errorf("Wrong profiles in a missing directory: %v %v", profiles, err)
+	}
+	if _, err := LoadProfile(profilesDir, "work"); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("No error for an unknown profile: %v", err)
+	}
+	if _, err := LoadProfile(profilesDir, "../work"); err == nil {
+		t.Errorf("No error for a bad profile name")
+	}
+
+	workProfile := Profile{Name: "work", Dir: filepath.Join(profilesDir, "work"), UserEmail: "me@corp.com", Format: "markdown",
+		Calendars: []string{"primary", "team@corp.com"}, ServiceAccount: "key.json"}
+	personalProfile := Profile{Name: "personal", Dir: filepath.Join(profilesDir, "personal"), Token: "/secrets/token.json"}
+	for _, profile := range []Profile{workProfile, personalProfile} {
+		if err := SaveProfile(profile); err != nil {
+			t.Errorf("Error while saving profile %v: %v", profile.Name, err)
+			return
+		}
+	}
+	profiles, err = ListProfiles(profilesDir)
+	if err != nil || len(profiles) != 2 || profiles[0].Name != "personal" || profiles[1].Name != "work" {
+		t.Errorf("Wrong profiles: %v %v", profiles, err)
+		return
+	}
+	if profiles[1].UserEmail != "me@corp.com" || profiles[1].Format != "markdown" || len(profiles[1].Calendars) != 2 {
+		t.Errorf("Wrong settings of the profile: %v", profiles[1])
+	}
+	// relative paths are in the directory of the profile
+	if profiles[0].GetCredentialsFileName() != filepath.Join(profilesDir, "personal", "credentials.json") ||
+		profiles[0].GetTokenFileName() != "/secrets/token.json" ||
+		profiles[1].GetServiceAccountFileName() != filepath.Join(profilesDir, "work", "key.json") ||
+		profiles[0].GetServiceAccountFileName() != "" {
+		t.Errorf("Wrong paths of the profiles")
+	}
+	// the key of the service account is missing until written
+	if profiles[0].IsLoggedIn() || profiles[1].IsLoggedIn() {
+		t.Errorf("Wrong login status of the profiles")
+	}
+	os.WriteFile(profiles[1].GetServiceAccountFileName(), []byte("{}"), 0600)
+	if !profiles[1].IsLoggedIn() {
+		t.Errorf("Profile with a service account key not logged in")
+	}
+}
+
+func TestLogout(t *testing.T) {
+	var revokedTokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		revokedTokens = append(revokedTokens, r.Form.Get("token"))
+		// the second token was already revoked
+		if len(revokedTokens) > 1 {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	defaultTokenRevokeURL := tokenRevokeURL
+	defer func() { tokenRevokeURL = defaultTokenRevokeURL }()
+	tokenRevokeURL = server.URL
+
+	tokenFileName := filepath.Join(t.TempDir(), "token.json")
+	for _, token := range []*oauth2.Token{{AccessToken: "access", RefreshToken: "refresh"}, {AccessToken: "access"}} {
+		saveToken(tokenFileName, token)
+		if err := Logout(tokenFileName); err != nil {
+			t.Errorf("Error while logging out: %v", err)
+		}
+		if _, err := os.Stat(tokenFileName); err == nil {
+			t.Errorf("Token file not deleted")
+		}
+	}
+	if len(revokedTokens) != 2 || revokedTokens[0] != "refresh" || revokedTokens[1] != "access" {
+		t.Errorf("Wrong revoked tokens: %v", revokedTokens)
+	}
+	if err := Logout(tokenFileName); err == nil {
+		t.Errorf("No error when not logged in")
+	}
+}
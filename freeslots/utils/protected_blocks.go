@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CalendarId of the synthetic busy events created for protected blocks
+const ProtectedBlockCalendarId = "protected-block"
+
+// recurring time that must never be reported as free, e.g. lunch
+type ProtectedBlock struct {
+	Label    string
+	Schedule WeeklySchedule
+}
+
+// parse a block in the form label[@day[-day]]=HH:MM-HH:MM[+HH:MM-HH:MM...], e.g. lunch=12:30-13:30
+// or standup@mon-fri=09:30-09:45. Blocks without days apply every day
+func ParseProtectedBlock(block string) (ProtectedBlock, error) {
+	labelAndDays, windows, found := strings.Cut(strings.TrimSpace(block), "=")
+	label, days, hasDays := strings.Cut(labelAndDays, "@")
+	label = strings.TrimSpace(label)
+	if !found || label == "" {
+		return ProtectedBlock{}, fmt.Errorf("bad block %q, expected label[@days]=HH:MM-HH:MM", block)
+	}
+	if !hasDays {
+		days = "sun-sat"
+	}
+	weeklySchedule, err := ParseWeeklySchedule(days + "=" + windows)
+	if err != nil {
+		return ProtectedBlock{}, fmt.Errorf("block %s: %w", label, err)
+	}
+	return ProtectedBlock{Label: label, Schedule: weeklySchedule}, nil
+}
+
+// read blocks from a file, one per line in the form accepted by ParseProtectedBlock.
+// Empty lines and lines starting with # are skipped
+func ReadProtectedBlocksFile(fileName string) ([]ProtectedBlock, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	protectedBlocks := []ProtectedBlock{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		protectedBlock, err := ParseProtectedBlock(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", fileName, lineNumber, err)
+		}
+		protectedBlocks = append(protectedBlocks, protectedBlock)
+	}
+	return protectedBlocks, scanner.Err()
+}
+
+// synthetic busy events of the blocks on the day of dailyAgenda, in order
+func GetProtectedBlockEvents(dailyAgenda DailyAgenda, protectedBlocks []ProtectedBlock) []CalendarEvent {
+	blockEvents := []CalendarEvent{}
+	for _, protectedBlock := range protectedBlocks {
+		for _, timeWindow := range protectedBlock.Schedule.GetWindows(dailyAgenda.Date) {
+			startTime := GetTimeWithSpecificHoursMinutes(dailyAgenda.Date, timeWindow.FromHours, timeWindow.FromMinutes)
+			endTime := GetTimeWithSpecificHoursMinutes(dailyAgenda.Date, timeWindow.ToHours, timeWindow.ToMinutes)
+			if !endTime.After(startTime) {
+				continue
+			}
+			blockEvents = append(blockEvents, CalendarEvent{
+				StartTime:   startTime,
+				Duration:    int(endTime.Sub(startTime).Minutes()),
+				Description: "protected: " + protectedBlock.Label,
+				Timezone:    startTime.Location().String(),
+				CalendarId:  ProtectedBlockCalendarId,
+			})
+		}
+	}
+	SortEventListByStartTime(&blockEvents)
+	return blockEvents
+}
+
+// add the synthetic busy events of the blocks to the events of the day
+func (dailyAgenda DailyAgenda) AddProtectedBlocks(protectedBlocks []ProtectedBlock) DailyAgenda {
+	return DailyAgenda{
+		Date:   dailyAgenda.Date,
+		Events: MergeCalendarEventLists(dailyAgenda.Events, GetProtectedBlockEvents(dailyAgenda, protectedBlocks)),
+	}
+}
+
+// remove the blocks from the free slots of the day. Descriptions of the free slots are kept
+func (dailyAgenda DailyAgenda) RemoveProtectedBlocks(protectedBlocks []ProtectedBlock) DailyAgenda {
+	blocksAgenda := DailyAgenda{Date: dailyAgenda.Date}.AddProtectedBlocks(protectedBlocks)
+	if blocksAgenda.IsEmpty() {
+		return dailyAgenda
+	}
+	outsideBlocksAgenda, _ := blocksAgenda.GetFreeSlots(0, 0, 0, 24, 0)
+	return IntersectFreeSlots(dailyAgenda, outsideBlocksAgenda)
+}
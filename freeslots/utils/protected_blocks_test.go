@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseProtectedBlock(t *testing.T) {
+	protectedBlock, err := ParseProtectedBlock("lunch=12:30-13:30")
+	if err != nil || protectedBlock.Label != "lunch" {
+		t.Errorf("Error while parsing block: %v", err)
+	}
+	for weekday := range protectedBlock.Schedule.Windows {
+		if len(protectedBlock.Schedule.Windows[weekday]) != 1 {
+			t.Errorf("Block without days not applied on %v", time.Weekday(weekday))
+		}
+	}
+	protectedBlock, err = ParseProtectedBlock("standup@mon-fri=09:30-09:45")
+	if err != nil || protectedBlock.Label != "standup" ||
+		len(protectedBlock.Schedule.Windows[time.Monday]) != 1 || len(protectedBlock.Schedule.Windows[time.Sunday]) != 0 {
+		t.Errorf("Wrong block with days: %v %v", protectedBlock, err)
+	}
+
+	badBlocks := []string{"lunch", "=12:30-13:30", "@mon=12:30-13:30", "lunch@xyz=12:30-13:30", "lunch=13:30-12:30"}
+	for _, badBlock := range badBlocks {
+		if _, err := ParseProtectedBlock(badBlock); err == nil {
+			t.Errorf("No error while parsing %q", badBlock)
+		}
+	}
+}
+
+func TestReadProtectedBlocksFile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "blocks.txt")
+	os.WriteFile(fileName, []byte("# daily breaks\nlunch=12:30-13:30\n\nstandup@mon-fri=09:30-09:45\n"), 0644)
+	protectedBlocks, err := ReadProtectedBlocksFile(fileName)
+	if err != nil || len(protectedBlocks) != 2 || protectedBlocks[1].Label != "standup" {
+		t.Errorf("Wrong blocks %v: %v", protectedBlocks, err)
+	}
+
+	os.WriteFile(fileName, []byte("lunch=12:30-13:30\nstandup\n"), 0644)
+	if _, err := ReadProtectedBlocksFile(fileName); err == nil {
+		t.Errorf("No error while reading a bad blocks file")
+	}
+}
+
+func TestFreeSlotsCoreWithProtectedBlocks(t *testing.T) {
+	lunch, _ := ParseProtectedBlock("lunch=12:30-13:30")
+	standup, _ := ParseProtectedBlock("standup@mon-fri=09:30-09:45")
+	// Friday and Saturday, busy 10:00-11:00 on Friday
+	dailyAgenda, _ := ParseDailyAgendaInLocation("d2025-12-12,m60,s10,aX", time.UTC)
+	freeSlotsCoreAlgorithm := FreeSlotsCoreAlgorithm{
+		NoDays:      2,
+		MinDuration: 30,
+		FromTime:    "09:00",
+		ToTime:      "18:00",
+		Format:      "plain",
+		StartDate:   time.Date(2025, time.December, 12, 0, 0, 0, 0, time.UTC),
+		Blocks:      []ProtectedBlock{lunch, standup},
+	}
+
+	var output bytes.Buffer
+	freeSlotsCoreAlgorithm.Output = &output
+	if err := freeSlotsCoreAlgorithm.FreeSlotsCore([]DailyAgenda{dailyAgenda}); err != nil {
+		t.Errorf("Error while getting free slots: %v", err)
+		return
+	}
+	expectedOutput := "12 Dec 2025: 09:00-09:30 UTC, 11:00-12:30 UTC, 13:30-18:00 UTC\n" +
+		"13 Dec 2025: 09:00-12:30 UTC, 13:30-18:00 UTC\n"
+	if output.String() != expectedOutput {
+		t.Errorf("Unexpected free slots: %q", output.String())
+	}
+
+	// blocks are listed with their label
+	output.Reset()
+	freeSlotsCoreAlgorithm.ShowAllEvents = true
+	if err := freeSlotsCoreAlgorithm.FreeSlotsCore([]DailyAgenda{dailyAgenda}); err != nil {
+		t.Errorf("Error while getting all events: %v", err)
+		return
+	}
+	expectedOutput = "12 Dec 2025: 09:30-09:45 UTC (protected: standup), 10:00-11:00 UTC (X), 12:30-13:30 UTC (protected: lunch)\n" +
+		"13 Dec 2025: 12:30-13:30 UTC (protected: lunch)\n"
+	if output.String() != expectedOutput {
+		t.Errorf("Unexpected events: %q", output.String())
+	}
+}
+
+func TestFreeSlotsCoreForAttendeesWithProtectedBlocks(t *testing.T) {
+	lunch, _ := ParseProtectedBlock("lunch=12:30-13:30")
+	bobAgenda, _ := ParseDailyAgendaInLocation("d2025-12-10,m60,s15,aX", time.UTC)
+	attendeeAgendas := []AttendeeAgenda{
+		{Email: "alice@x.com"},
+		{Email: "bob@x.com", DailyAgendas: []DailyAgenda{bobAgenda}},
+		{Email: "carol@x.com", Optional: true, DailyAgendas: []DailyAgenda{bobAgenda}},
+	}
+	var output bytes.Buffer
+	freeSlotsCoreAlgorithm := FreeSlotsCoreAlgorithm{
+		NoDays:      1,
+		MinDuration: 60,
+		FromTime:    "09:00",
+		ToTime:      "18:00",
+		Format:      "plain",
+		StartDate:   time.Date(2025, time.December, 10, 0, 0, 0, 0, time.UTC),
+		Quorum:      2,
+		Blocks:      []ProtectedBlock{lunch},
+		Output:      &output,
+	}
+	if err := freeSlotsCoreAlgorithm.FreeSlotsCoreForAttendees(attendeeAgendas); err != nil {
+		t.Errorf("Error while getting free slots: %v", err)
+		return
+	}
+	// missing optional attendees are still listed for the slots cut by the block
+	expectedOutput := "10 Dec 2025: 09:00-12:30 UTC (*), 13:30-15:00 UTC (*), 16:00-18:00 UTC (*)\n"
+	if output.String() != expectedOutput {
+		t.Errorf("Unexpected output: %q", output.String())
+	}
+}
@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// options of a single rendering
+// ShowAllEvents is set when agendas contain events, otherwise they contain free slots
+type RenderOptions struct {
+	ShowAllEvents    bool
+	ShowDescription  bool
+	ShowSlotDuration bool
+	// tells which of all the events make the user busy, all of them when not set
+	IsBusy func(event CalendarEvent) bool
+}
+
+// output format of agendas, e.g. plain text, HTML or Markdown
+// Render writes the whole output, including headers and footers, for the given agendas
+type Renderer interface {
+	Render(w io.Writer, dailyAgendas []DailyAgenda, renderOptions RenderOptions) error
+}
+
+var (
+	renderersMutex sync.RWMutex
+	renderers      = make(map[string]Renderer)
+)
+
+func init() {
+	RegisterRenderer("plain", PlainRenderer{})
+	RegisterRenderer("html", HtmlRenderer{})
+	RegisterRenderer("markdown", MarkdownRenderer{})
+}
+
+// register a renderer with a name, to be selected with the Format of FreeSlotsCoreAlgorithm.
+// Registering a name twice replaces the previous renderer
+func RegisterRenderer(name string, renderer Renderer) {
+	renderersMutex.Lock()
+	defer renderersMutex.Unlock()
+	renderers[name] = renderer
+}
+
+// return the renderer registered with the given name
+func GetRenderer(name string) (Renderer, error) {
+	renderersMutex.RLock()
+	renderer, found := renderers[name]
+	renderersMutex.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("unknown format %q, can be: %s", name, strings.Join(GetRendererNames(), ", "))
+	}
+	return renderer, nil
+}
+
+// return the sorted names of the registered renderers
+func GetRendererNames() []string {
+	renderersMutex.RLock()
+	defer renderersMutex.RUnlock()
+	names := make([]string, 0, len(renderers))
+	for name := range renderers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// writer keeping the first error of a sequence of writes, which are skipped after it, so that
+// the sequence can be checked once
+type firstErrorWriter struct {
+	writer io.Writer
+	err    error
+}
+
+func (w *firstErrorWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.writer.Write(p)
+	w.err = err
+	return n, err
+}
+
+// one line per day
+type PlainRenderer struct{}
+
+func (plainRenderer PlainRenderer) Render(w io.Writer, dailyAgendas []DailyAgenda, renderOptions RenderOptions) error {
+	for _, dailyAgenda := range dailyAgendas {
+		if err := dailyAgenda.Fprint(w, renderOptions.ShowDescription, renderOptions.ShowSlotDuration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HTML page with a table row per event or slot
+type HtmlRenderer struct{}
+
+func (htmlRenderer HtmlRenderer) Render(writer io.Writer, dailyAgendas []DailyAgenda, renderOptions RenderOptions) error {
+	w := &firstErrorWriter{writer: writer}
+	fmt.Fprint(w, "<html><style>table, th, td {  border: 1px solid black;  border-collapse: collapse;} </style> <body><table><tr><td>Date</td>")
+	switch {
+	case renderOptions.ShowAllEvents:
+		fmt.Fprint(w, "<td>Event</td><td>Description</td></tr>")
+	case renderOptions.ShowDescription:
+		fmt.Fprint(w, "<td>Slot</td><td>Description</td></tr>")
+	default:
+		fmt.Fprint(w, "<td>Slot</td></tr>")
+	}
+	for _, dailyAgenda := range dailyAgendas {
+		dailyAgenda.FprintHtml(w, renderOptions.ShowDescription, renderOptions.ShowSlotDuration)
+	}
+	fmt.Fprintln(w, "</table></body></html>")
+	return w.err
+}
+
+// Markdown table with a row per event or slot
+type MarkdownRenderer struct{}
+
+func (markdownRenderer MarkdownRenderer) Render(writer io.Writer, dailyAgendas []DailyAgenda, renderOptions RenderOptions) error {
+	w := &firstErrorWriter{writer: writer}
+	switch {
+	case renderOptions.ShowAllEvents:
+		fmt.Fprintln(w, "| Date | Event | Description |")
+		fmt.Fprintln(w, "| -------- | -------- | -------- |")
+	case renderOptions.ShowDescription:
+		fmt.Fprintln(w, "| Date | Slot | Description |")
+		fmt.Fprintln(w, "| ----------- | ----------- | ----------- |")
+	default:
+		fmt.Fprintln(w, "| Date | Slot |")
+		fmt.Fprintln(w, "| ----------- | ----------- |")
+	}
+	for _, dailyAgenda := range dailyAgendas {
+		dailyAgenda.FprintMarkdown(w, renderOptions.ShowDescription, renderOptions.ShowSlotDuration)
+	}
+	return w.err
+}
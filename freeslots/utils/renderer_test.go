@@ -0,0 +1,181 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// renderer defined outside the registry defaults, counting the events of each day
+type testRenderer struct{}
+
+func (renderer testRenderer) Render(w io.Writer, dailyAgendas []DailyAgenda, renderOptions RenderOptions) error {
+	for _, dailyAgenda := range dailyAgendas {
+		fmt.Fprintf(w, "%s=%d\n", dailyAgenda.Date.Format(time.DateOnly), len(dailyAgenda.Events))
+	}
+	return nil
+}
+
+// remove a registered renderer, to restore the registry
+func unregisterRenderer(name string) {
+	renderersMutex.Lock()
+	defer renderersMutex.Unlock()
+	delete(renderers, name)
+}
+
+func TestRenderers(t *testing.T) {
+	dailyAgenda, _ := ParseDailyAgenda("d2025-12-10,m30,s16,aXX--YY")
+	formats := []string{"plain", "html", "markdown", "test"}
+	expectedOutputs := [][]string{
+		{"10 Dec 2025: 08:00-09:00", " (X), 10:00-11:00", " (Y)\n"},
+		{"<td>Event</td><td>Description</td>", "<tr><td>10 Dec 2025</td><td>08:00-09:00", "<td>Y</td></tr>\n", "</table></body></html>\n"},
+		{"| Date | Event | Description |\n", "| 10 Dec 2025 | 08:00-09:00", " | X |\n"},
+		{"2025-12-10=2\n"},
+	}
+	RegisterRenderer("test", testRenderer{})
+	t.Cleanup(func() { unregisterRenderer("test") })
+	for formatIndex, format := range formats {
+		var output bytes.Buffer
+		freeSlotsCoreAlgorithm := FreeSlotsCoreAlgorithm{
+			ShowAllEvents: true,
+			Format:        format,
+			Output:        &output,
+		}
+		if err := freeSlotsCoreAlgorithm.FreeSlotsCore([]DailyAgenda{dailyAgenda}); err != nil {
+			t.Errorf("Error while rendering format %v: %v", format, err)
+			continue
+		}
+		for _, expectedOutput := range expectedOutputs[formatIndex] {
+			if !strings.Contains(output.String(), expectedOutput) {
+				t.Errorf("Error while rendering format %v: %q not found in %q", format, expectedOutput, output.String())
+			}
+		}
+	}
+
+	freeSlotsCoreAlgorithm := FreeSlotsCoreAlgorithm{
+		ShowAllEvents: true,
+		Format:        "missing",
+		Output:        io.Discard,
+	}
+	if err := freeSlotsCoreAlgorithm.FreeSlotsCore([]DailyAgenda{dailyAgenda}); err == nil {
+		t.Errorf("Error expected for an unknown format")
+	}
+}
+
+func TestJsonRenderer(t *testing.T) {
+	location, _ := time.LoadLocation("Europe/Rome")
+	currentDay := time.Date(2025, 12, 10, 0, 0, 0, 0, location)
+	dailyAgendas := []DailyAgenda{
+		{Date: currentDay, Events: []CalendarEvent{
+			{StartTime: currentDay, Duration: 24 * 60, Description: "W", AllDay: true},
+			{StartTime: currentDay.Add(9 * time.Hour), Duration: 60, Description: "X"},
+			{StartTime: currentDay.Add(14*time.Hour + 30*time.Minute), Duration: 30, Description: "Y"},
+		}},
+	}
+	renderOptionsList := []RenderOptions{{}, {ShowAllEvents: true, ShowDescription: true}}
+	expectedOutputs := []string{
+		`{"schemaVersion":1,"kind":"freeSlots","days":[{"date":"2025-12-10","timezone":"Europe/Rome","slots":[` +
+			`{"start":"2025-12-10T00:00:00+01:00","end":"2025-12-11T00:00:00+01:00","durationMinutes":1440,"allDay":true},` +
+			`{"start":"2025-12-10T09:00:00+01:00","end":"2025-12-10T10:00:00+01:00","durationMinutes":60,"allDay":false},` +
+			`{"start":"2025-12-10T14:30:00+01:00","end":"2025-12-10T15:00:00+01:00","durationMinutes":30,"allDay":false}]}]}`,
+		`{"schemaVersion":1,"kind":"events","days":[{"date":"2025-12-10","timezone":"Europe/Rome","slots":[` +
+			`{"start":"2025-12-10T00:00:00+01:00","end":"2025-12-11T00:00:00+01:00","durationMinutes":1440,"allDay":true,"description":"W"},` +
+			`{"start":"2025-12-10T09:00:00+01:00","end":"2025-12-10T10:00:00+01:00","durationMinutes":60,"allDay":false,"description":"X"},` +
+			`{"start":"2025-12-10T14:30:00+01:00","end":"2025-12-10T15:00:00+01:00","durationMinutes":30,"allDay":false,"description":"Y"}]}]}`,
+	}
+	for index, renderOptions := range renderOptionsList {
+		var output, compactOutput bytes.Buffer
+		if err := (JsonRenderer{}).Render(&output, dailyAgendas, renderOptions); err != nil {
+			t.Errorf("Error while rendering JSON: %v", err)
+			continue
+		}
+		if err := json.Compact(&compactOutput, output.Bytes()); err != nil {
+			t.Errorf("Invalid JSON %q: %v", output.String(), err)
+			continue
+		}
+		if compactOutput.String() != expectedOutputs[index] {
+			t.Errorf("Error while rendering JSON:\n got %s\nwant %s", compactOutput.String(), expectedOutputs[index])
+		}
+	}
+
+	var output bytes.Buffer
+	if err := (JsonRenderer{}).Render(&output, nil, RenderOptions{}); err != nil || !strings.Contains(output.String(), `"days": []`) {
+		t.Errorf("Error while rendering JSON without days: %q %v", output.String(), err)
+	}
+
+	// the local time zone is named after $TZ, or by its offset when not known
+	localDay := time.Date(2025, 12, 10, 0, 0, 0, 0, time.Local)
+	for tz, expectedName := range map[string]string{
+		"America/New_York":  "America/New_York",
+		":Asia/Tokyo":       "Asia/Tokyo",
+		"":                  "UTC",
+		"Mars/Olympus_Mons": localDay.Format("-07:00"),
+		"/etc/zone-of-rome": localDay.Format("-07:00"),
+	} {
+		t.Setenv("TZ", tz)
+		if name := getTimezoneName(localDay); name != expectedName {
+			t.Errorf("Wrong name of the local time zone with TZ=%q: %v", tz, name)
+		}
+	}
+}
+
+func TestRenderAllDayEvents(t *testing.T) {
+	rome, _ := time.LoadLocation("Europe/Rome")
+	date := time.Date(2025, time.December, 10, 0, 0, 0, 0, rome)
+	dailyAgenda := DailyAgenda{Date: date, Events: []CalendarEvent{
+		{StartTime: date, Duration: 24 * 60, Description: "Conference", AllDay: true},
+		{StartTime: date.Add(9 * time.Hour), Duration: 60, Description: "Meeting"},
+	}}
+	expectedOutputs := map[string]string{
+		"plain":    "10 Dec 2025: all day (Conference), 09:00-10:00 CET (60') (Meeting)\n",
+		"html":     "<tr><td>10 Dec 2025</td><td>all day</td><td>Conference</td></tr>\n<tr><td>10 Dec 2025</td><td>09:00-10:00 CET (60')</td><td>Meeting</td></tr>\n",
+		"markdown": "| 10 Dec 2025 | all day | Conference |\n| 10 Dec 2025 | 09:00-10:00 CET (60') | Meeting |\n",
+	}
+	for format, expectedOutput := range expectedOutputs {
+		renderer, _ := GetRenderer(format)
+		var output bytes.Buffer
+		renderOptions := RenderOptions{ShowAllEvents: true, ShowDescription: true, ShowSlotDuration: true}
+		if err := renderer.Render(&output, []DailyAgenda{dailyAgenda}, renderOptions); err != nil {
+			t.Errorf("Error while rendering format %v: %v", format, err)
+			continue
+		}
+		if !strings.Contains(output.String(), expectedOutput) {
+			t.Errorf("Error while rendering format %v: %q not found in %q", format, expectedOutput, output.String())
+		}
+	}
+}
+
+// writer failing from the given write on
+type failingWriter struct {
+	writesLeft int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.writesLeft == 0 {
+		return 0, fmt.Errorf("disk full")
+	}
+	w.writesLeft--
+	return len(p), nil
+}
+
+func TestRenderWriteErrors(t *testing.T) {
+	dailyAgenda, _ := ParseDailyAgenda("d2025-12-10,m30,s16,aXX--YY")
+	// json is written at once, so it is left out
+	for _, format := range []string{"plain", "html", "markdown", "ics"} {
+		// the first write error is returned, wherever it happens
+		for writesLeft := 0; writesLeft < 3; writesLeft++ {
+			freeSlotsCoreAlgorithm := FreeSlotsCoreAlgorithm{
+				ShowAllEvents: true,
+				Format:        format,
+				Output:        &failingWriter{writesLeft: writesLeft},
+			}
+			if err := freeSlotsCoreAlgorithm.FreeSlotsCore([]DailyAgenda{dailyAgenda}); err == nil || err.Error() != "disk full" {
+				t.Errorf("Write error not returned for format %v after %v writes: %v", format, writesLeft, err)
+			}
+		}
+	}
+}
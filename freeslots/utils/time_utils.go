@@ -1,7 +1,11 @@
 package utils
 
 import (
+	"context"
 	"fmt"
+	"html"
+	"io"
+	"os"
 	"slices"
 	"strconv"
 	"strings"
@@ -10,11 +14,27 @@ import (
 	"google.golang.org/api/calendar/v3"
 )
 
+// event of a calendar, or free slot
 type CalendarEvent struct {
-	StartTime   time.Time
+	StartTime time.Time
+	// elapsed time in minutes, which differs from the wall clock one across DST transitions: always get
+	// the end with GetEndTime
 	Duration    int
 	Description string
 	Timezone    string
+	CalendarId  string
+	// location of the event, if any
+	Place string
+	// conference link of the event, if any
+	VideoLink string
+	// lasts, or its portion on each day, from midnight to midnight
+	AllDay bool
+	// Status, Transparency, EventType and the ResponseStatus of the user decide whether the event makes
+	// the user busy, see BusyPolicy
+	Status         string
+	Transparency   string
+	EventType      string
+	ResponseStatus string
 }
 
 func (calendarEvent CalendarEvent) GetEndTime() time.Time {
@@ -23,6 +43,10 @@ func (calendarEvent CalendarEvent) GetEndTime() time.Time {
 	return endDate
 }
 
+func (calendarEvent CalendarEvent) HasPlaceOrVideoLink() bool {
+	return calendarEvent.Place != "" || calendarEvent.VideoLink != ""
+}
+
 func (event CalendarEvent) Print() {
 	fmt.Printf("%v - %v - %v - %v\n", event.StartTime,
 		event.Duration, event.Description, event.Timezone)
@@ -34,55 +58,70 @@ type DailyAgenda struct {
 }
 
 func (dailyAgenda DailyAgenda) Print(showDescription, showSlotDuration bool) {
-	fmt.Printf("%s: ", dailyAgenda.Date.Format("2 Jan 2006"))
+	dailyAgenda.Fprint(os.Stdout, showDescription, showSlotDuration)
+}
+
+// write the agenda on a line, returning the first write error
+func (dailyAgenda DailyAgenda) Fprint(writer io.Writer, showDescription, showSlotDuration bool) error {
+	w := &firstErrorWriter{writer: writer}
+	fmt.Fprintf(w, "%s: ", dailyAgenda.Date.Format("2 Jan 2006"))
 	for index, event := range dailyAgenda.Events {
 		if index > 0 {
-			fmt.Print(", ")
-		}
-		if showSlotDuration {
-			fmt.Printf("%s-%s (%v')", event.StartTime.Format("15:04"), event.GetEndTime().Format("15:04 MST"),
-				event.Duration)
-
-		} else {
-			fmt.Printf("%s-%s", event.StartTime.Format("15:04"), event.GetEndTime().Format("15:04 MST"))
+			fmt.Fprint(w, ", ")
 		}
+		fmt.Fprint(w, event.FormatTimeRange(showSlotDuration))
 		if showDescription {
-			fmt.Printf(" (%s)", event.Description)
+			fmt.Fprintf(w, " (%s)", event.Description)
 		}
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
+	return w.err
+}
+
+// start and end time of the event, e.g. "09:00-10:00 CET (60')" with the duration, or "all day"
+func (event CalendarEvent) FormatTimeRange(showSlotDuration bool) string {
+	if event.AllDay {
+		return "all day"
+	}
+	timeRange := event.StartTime.Format("15:04") + "-" + event.GetEndTime().Format("15:04 MST")
+	if showSlotDuration {
+		timeRange += fmt.Sprintf(" (%v')", event.Duration)
+	}
+	return timeRange
 }
 
 func (dailyAgenda DailyAgenda) PrintHtml(showDescription, showSlotDuration bool) {
+	dailyAgenda.FprintHtml(os.Stdout, showDescription, showSlotDuration)
+}
+
+// write a table row per event, returning the first write error
+func (dailyAgenda DailyAgenda) FprintHtml(writer io.Writer, showDescription, showSlotDuration bool) error {
+	w := &firstErrorWriter{writer: writer}
 	for _, event := range dailyAgenda.Events {
-		if showSlotDuration {
-			fmt.Printf("<tr><td>%s</td><td>%s-%s (%v')</td>", dailyAgenda.Date.Format("2 Jan 2006"),
-				event.StartTime.Format("15:04"), event.GetEndTime().Format("15:04 MST"), event.Duration)
-		} else {
-			fmt.Printf("<tr><td>%s</td><td>%s-%s</td>", dailyAgenda.Date.Format("2 Jan 2006"),
-				event.StartTime.Format("15:04"), event.GetEndTime().Format("15:04 MST"))
-		}
+		fmt.Fprintf(w, "<tr><td>%s</td><td>%s</td>", dailyAgenda.Date.Format("2 Jan 2006"), event.FormatTimeRange(showSlotDuration))
 		if showDescription {
-			fmt.Printf("<td>%s</td>", event.Description)
+			fmt.Fprintf(w, "<td>%s</td>", html.EscapeString(event.Description))
 		}
-		fmt.Println("</tr>")
+		fmt.Fprintln(w, "</tr>")
 	}
+	return w.err
 }
 
 func (dailyAgenda DailyAgenda) PrintMarkdown(showDescription, showSlotDuration bool) {
+	dailyAgenda.FprintMarkdown(os.Stdout, showDescription, showSlotDuration)
+}
+
+// write a table row per event, returning the first write error
+func (dailyAgenda DailyAgenda) FprintMarkdown(writer io.Writer, showDescription, showSlotDuration bool) error {
+	w := &firstErrorWriter{writer: writer}
 	for _, event := range dailyAgenda.Events {
-		if showSlotDuration {
-			fmt.Printf("| %s | %s-%s (%v') |", dailyAgenda.Date.Format("2 Jan 2006"),
-				event.StartTime.Format("15:04"), event.GetEndTime().Format("15:04 MST"), event.Duration)
-		} else {
-			fmt.Printf("| %s | %s-%s |", dailyAgenda.Date.Format("2 Jan 2006"),
-				event.StartTime.Format("15:04"), event.GetEndTime().Format("15:04 MST"))
-		}
+		fmt.Fprintf(w, "| %s | %s |", dailyAgenda.Date.Format("2 Jan 2006"), event.FormatTimeRange(showSlotDuration))
 		if showDescription {
-			fmt.Printf("| %s |", event.Description)
+			fmt.Fprintf(w, " %s |", event.Description)
 		}
-		fmt.Println()
+		fmt.Fprintln(w)
 	}
+	return w.err
 }
 
 func (dailyAgenda DailyAgenda) IsEmpty() bool {
@@ -93,8 +132,45 @@ func (dailyAgenda DailyAgenda) IsWeekend() bool {
 	return dailyAgenda.Date.Weekday() == time.Sunday || dailyAgenda.Date.Weekday() == time.Saturday
 }
 
+// time of the day of origTime at the given wall clock hours and minutes, see GetWallClockTime
 func GetTimeWithSpecificHoursMinutes(origTime time.Time, hours, minutes int) time.Time {
-	return time.Date(origTime.Year(), origTime.Month(), origTime.Day(), hours, minutes, 0, 0, origTime.Location())
+	return GetWallClockTime(origTime.Year(), origTime.Month(), origTime.Day(), hours, minutes, origTime.Location())
+}
+
+// instant of the given wall clock date and time in location. Unlike time.Date, times skipped by a DST
+// transition are moved to the end of the gap: e.g. midnight of the first day of DST in America/Santiago
+// is 01:00 of the same day, not 23:00 of the day before. Values out of range are normalized like time.Date
+func GetWallClockTime(year int, month time.Month, day, hours, minutes int, location *time.Location) time.Time {
+	wallClock := time.Date(year, month, day, hours, minutes, 0, 0, time.UTC)
+	localTime := time.Date(year, month, day, hours, minutes, 0, 0, location)
+	localWallClock := time.Date(localTime.Year(), localTime.Month(), localTime.Day(),
+		localTime.Hour(), localTime.Minute(), 0, 0, time.UTC)
+	switch localWallClock.Compare(wallClock) {
+	case -1:
+		// moved before the gap
+		_, zoneEnd := localTime.ZoneBounds()
+		return zoneEnd
+	case 1:
+		// moved after the gap
+		zoneStart, _ := localTime.ZoneBounds()
+		return zoneStart
+	}
+	return localTime
+}
+
+// start of the day that comes the given number of days after the day of origTime.
+// Days last 23 or 25 hours across DST transitions, so AddDate on the start of a day is not enough
+func GetPureDateAfterDays(origTime time.Time, days int) time.Time {
+	return GetWallClockTime(origTime.Year(), origTime.Month(), origTime.Day()+days, 0, 0, origTime.Location())
+}
+
+// parse a date with the given layout, returning the start of that day in location
+func ParseDateInLocation(layout, value string, location *time.Location) (time.Time, error) {
+	parsedDate, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return GetWallClockTime(parsedDate.Year(), parsedDate.Month(), parsedDate.Day(), 0, 0, location), nil
 }
 
 // print event list
@@ -108,21 +184,29 @@ func GetPureDate(origTime time.Time) time.Time {
 	return GetTimeWithSpecificHoursMinutes(origTime, 0, 0)
 }
 
+// true if the two times fall on the same calendar day, each one in its own time zone
+func IsSameDate(firstTime, secondTime time.Time) bool {
+	firstYear, firstMonth, firstDay := firstTime.Date()
+	secondYear, secondMonth, secondDay := secondTime.Date()
+	return firstYear == secondYear && firstMonth == secondMonth && firstDay == secondDay
+}
+
+// compare the wall clocks of two times, as if they were in the same time zone
 func CompareTimesWithoutTimeZone(firstTime, secondTime time.Time) int {
 	newFirstTime := time.Date(firstTime.Year(), firstTime.Month(), firstTime.Day(),
 		firstTime.Hour(), firstTime.Minute(), firstTime.Second(), 0, secondTime.Location())
 	return newFirstTime.Compare(secondTime)
 }
 
+// event starting on currentDay at the given time, in the time zone of currentDay
 func CreateDefaultCalendarEvent(currentDay time.Time, startTimeHour int, startTimeMin int, duration int, description string) CalendarEvent {
 	now := currentDay
 	calendarEvent := CalendarEvent{
 		Duration:    duration,
 		Description: description,
-		Timezone:    now.Local().String(),
+		Timezone:    now.Location().String(),
 	}
-	StartTime := time.Date(now.Year(), now.Month(), now.Day(), startTimeHour, startTimeMin,
-		0, 0, now.Local().Location())
+	StartTime := GetWallClockTime(now.Year(), now.Month(), now.Day(), startTimeHour, startTimeMin, now.Location())
 	calendarEvent.StartTime = StartTime
 	return calendarEvent
 }
@@ -132,17 +216,26 @@ func CreateDefaultCalendarEventFromString(currentDay time.Time, startTime string
 	calendarEvent := CalendarEvent{
 		Duration:    duration,
 		Description: description,
-		Timezone:    now.Local().String(),
+		Timezone:    now.Location().String(),
 	}
 	startTimeParts := strings.Split(startTime, ":")
 	hours, _ := strconv.Atoi(startTimeParts[0])
 	mins, _ := strconv.Atoi(startTimeParts[1])
-	StartTime := time.Date(now.Year(), now.Month(), now.Day(), hours, mins,
-		0, 0, now.Local().Location())
+	StartTime := GetWallClockTime(now.Year(), now.Month(), now.Day(), hours, mins, now.Location())
 	calendarEvent.StartTime = StartTime
 	return calendarEvent
 }
 
+// event between the given wall clock minutes from midnight of currentDay, e.g. 60-240 for 01:00-04:00.
+// Its duration is the elapsed time, which is 120 minutes if the clocks are moved forward at 02:00
+func createWallClockCalendarEvent(currentDay time.Time, startMinutes int, durationMinutes int, description string) CalendarEvent {
+	calendarEvent := CreateDefaultCalendarEvent(currentDay, startMinutes/60, startMinutes%60, durationMinutes, description)
+	endTime := GetWallClockTime(currentDay.Year(), currentDay.Month(), currentDay.Day(), 0, startMinutes+durationMinutes,
+		currentDay.Location())
+	calendarEvent.Duration = int(endTime.Sub(calendarEvent.StartTime).Minutes())
+	return calendarEvent
+}
+
 // parse time in the form "HH:MM"
 func ParseTime(timeAsString string) (int, int) {
 	parts := strings.Split(timeAsString, ":")
@@ -157,78 +250,123 @@ func SortEventListByStartTime(eventList *[]CalendarEvent) {
 	})
 }
 
-// Get events from Google Calendar
-func GetEventsFromPrimaryCalendar(srv *calendar.Service, tMin time.Time, noDays int, userMail string) ([]DailyAgenda, error) {
-	// TODO: add option to manage multiple calendars
+// Get the IDs of all the calendars in the calendar list of the user
+func GetCalendarIds(srv *calendar.Service) ([]string, error) {
+	calendarIds := []string{}
+	err := srv.CalendarList.List().Pages(context.Background(), func(calendarList *calendar.CalendarList) error {
+		for _, item := range calendarList.Items {
+			calendarIds = append(calendarIds, item.Id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return calendarIds, nil
+}
+
+// get events from a single Google Calendar, sorted by start time
+func getEventsFromCalendar(ctx context.Context, srv *calendar.Service, calendarId string, tMin, tMax time.Time, userMail string) ([]CalendarEvent, error) {
 	tMinAsString := tMin.Format(time.RFC3339)
-	tMaxAsString := tMin.AddDate(0, 0, noDays).Format(time.RFC3339)
-	events, err := srv.Events.List("primary").
+	tMaxAsString := tMax.Format(time.RFC3339)
+	// busy shared calendars can have more events than a page holds
+	items := []*calendar.Event{}
+	err := srv.Events.List(calendarId).
 		ShowDeleted(false).
 		SingleEvents(true).
 		TimeMin(tMinAsString).
 		TimeMax(tMaxAsString).
 		MaxResults(2500).
 		OrderBy("startTime").
-		Do()
+		Pages(ctx, func(events *calendar.Events) error {
+			items = append(items, events.Items...)
+			return nil
+		})
 	if err != nil {
 		return nil, err
 	}
 
 	eventList := []CalendarEvent{}
-	for _, item := range events.Items {
-		// scanning attendees to see if I declined the event
-		// TODO: fix
-		canAttend := true
+	for _, item := range items {
+		// scanning attendees to get my response
+		responseStatus := ""
 		for _, attendee := range item.Attendees {
-			if attendee.Email == userMail && attendee.ResponseStatus == "declined" {
-				canAttend = false
+			if attendee.Self || attendee.Email == userMail {
+				responseStatus = attendee.ResponseStatus
 				break
 			}
 		}
-		if !canAttend {
+		// declined and cancelled events are not shown at all
+		if responseStatus == ResponseDeclined || item.Status == StatusCancelled {
 			continue
 		}
 
 		newEvent := CalendarEvent{}
+		newEvent.Status = item.Status
+		newEvent.Transparency = item.Transparency
+		newEvent.EventType = item.EventType
+		newEvent.ResponseStatus = responseStatus
 		newEvent.Description = item.Summary
 		newEvent.Timezone = item.Start.TimeZone
+		newEvent.CalendarId = calendarId
+		newEvent.Place = item.Location
+		newEvent.VideoLink = getVideoLink(item)
+		// events are split into days in the time zone of the requested range,
+		// all-day events start at midnight of that time zone
+		isAllDay := item.Start.DateTime == ""
 		from := item.Start.DateTime
 		if from == "" {
-			newEvent.StartTime, _ = time.Parse(time.DateOnly, item.Start.Date)
+			newEvent.StartTime, _ = ParseDateInLocation(time.DateOnly, item.Start.Date, tMin.Location())
 		} else {
 			newEvent.StartTime, _ = time.Parse(time.RFC3339, from)
+			newEvent.StartTime = newEvent.StartTime.In(tMin.Location())
 		}
 		end := item.End.DateTime
 		if end == "" {
-			endDate, _ := time.Parse(time.DateOnly, item.End.Date)
+			endDate, _ := ParseDateInLocation(time.DateOnly, item.End.Date, tMin.Location())
 			newEvent.Duration = int(endDate.Sub(newEvent.StartTime).Minutes())
 		} else {
 			endDate, _ := time.Parse(time.RFC3339, end)
 			newEvent.Duration = int(endDate.Sub(newEvent.StartTime).Minutes())
 		}
-		// skip all day-events
-		if newEvent.Duration != 1440 {
-			eventList = append(eventList, newEvent)
-		}
+		// all-day events are kept, FreeSlotsCoreAlgorithm decides whether they make the user busy
+		newEvent.AllDay = isAllDay
+		eventList = append(eventList, newEvent)
 	}
 
 	SortEventListByStartTime(&eventList)
-	var dailyAgendas []DailyAgenda = SplitCalendarEventsByDay(eventList)
-	return dailyAgendas, nil
+	return eventList, nil
+}
+
+// link of the video conference of a Google Calendar event, if any
+func getVideoLink(item *calendar.Event) string {
+	if item.ConferenceData != nil {
+		for _, entryPoint := range item.ConferenceData.EntryPoints {
+			if entryPoint.EntryPointType == "video" {
+				return entryPoint.Uri
+			}
+		}
+	}
+	return item.HangoutLink
 }
 
 func ParseDailyAgenda(singleDayAgenda string) (DailyAgenda, error) {
-	events, err := ParseSingleDayAgenda(singleDayAgenda)
+	return ParseDailyAgendaInLocation(singleDayAgenda, time.Local)
+}
+
+// parse a daily agenda whose times are in the given location, see ParseSingleDayAgenda
+func ParseDailyAgendaInLocation(singleDayAgenda string, location *time.Location) (DailyAgenda, error) {
+	events, err := ParseSingleDayAgendaInLocation(singleDayAgenda, location)
 	if err != nil {
 		return DailyAgenda{}, err
 	}
 	singleDayAgendaParts := strings.Split(singleDayAgenda, ",")
-	currentDay := GetPureDate(time.Now())
+	currentDay := GetPureDate(time.Now().In(location))
 	for _, part := range singleDayAgendaParts {
 		switch part[0] {
 		case 'd':
 			dateStr := part[1:]
-			parsedTime, err := time.Parse(time.DateOnly, dateStr)
+			parsedTime, err := ParseDateInLocation(time.DateOnly, dateStr, location)
 			if err != nil {
 				return DailyAgenda{}, err
 			}
@@ -243,6 +381,11 @@ func ParseDailyAgenda(singleDayAgenda string) (DailyAgenda, error) {
 }
 
 func ParseSingleDayAgenda(singleDayAgenda string) ([]CalendarEvent, error) {
+	return ParseSingleDayAgendaInLocation(singleDayAgenda, time.Local)
+}
+
+// parse a single day agenda whose times are in the given location
+func ParseSingleDayAgendaInLocation(singleDayAgenda string, location *time.Location) ([]CalendarEvent, error) {
 	// Format:
 	// d<yyyy-mm-dd>,m<number>,s<number>,a<agenda>
 	// d<yyyy-mm-dd> = date of the single day agenda (pay attention to the format!)
@@ -262,17 +405,16 @@ func ParseSingleDayAgenda(singleDayAgenda string) ([]CalendarEvent, error) {
 	slotDuration := 60
 	slotsToSkip := 0
 	dailyAgendasToScan := make([]string, 0)
-	currentDay := GetPureDate(time.Now())
+	currentDay := GetPureDate(time.Now().In(location))
 	for _, part := range singleDayAgendaParts {
 		switch part[0] {
 		case 'd':
 			dateStr := part[1:]
-			parsedTime, err := time.Parse(time.DateOnly, dateStr)
+			parsedTime, err := ParseDateInLocation(time.DateOnly, dateStr, location)
 			if err != nil {
 				return nil, err
 			}
 			currentDay = parsedTime
-			// TODO: set time zone
 		case 'm':
 			minStr := part[1:]
 			slotDuration, _ = strconv.Atoi(minStr)
@@ -299,7 +441,7 @@ func ParseSingleDayAgenda(singleDayAgenda string) ([]CalendarEvent, error) {
 					continue
 				}
 				// finalize calendar event and store it
-				newCalendarEvent := CreateDefaultCalendarEvent(currentDay, currentEventStartHour, currentEventStartMin,
+				newCalendarEvent := createWallClockCalendarEvent(currentDay, currentEventStartHour*60+currentEventStartMin,
 					currentEventDuration, currentEventDescription)
 				calendarEvents = append(calendarEvents, newCalendarEvent)
 				previousChar = currentChar
@@ -321,7 +463,7 @@ func ParseSingleDayAgenda(singleDayAgenda string) ([]CalendarEvent, error) {
 					if previousChar == currentChar {
 						currentEventDuration += slotDuration
 					} else {
-						newCalendarEvent := CreateDefaultCalendarEvent(currentDay, currentEventStartHour, currentEventStartMin,
+						newCalendarEvent := createWallClockCalendarEvent(currentDay, currentEventStartHour*60+currentEventStartMin,
 							currentEventDuration, currentEventDescription)
 						calendarEvents = append(calendarEvents, newCalendarEvent)
 						currentEventDescription = string(currentChar)
@@ -336,7 +478,7 @@ func ParseSingleDayAgenda(singleDayAgenda string) ([]CalendarEvent, error) {
 		}
 		if previousChar != '-' {
 			// finalize calendar event and store it
-			newCalendarEvent := CreateDefaultCalendarEvent(currentDay, currentEventStartHour, currentEventStartMin,
+			newCalendarEvent := createWallClockCalendarEvent(currentDay, currentEventStartHour*60+currentEventStartMin,
 				currentEventDuration, currentEventDescription)
 			calendarEvents = append(calendarEvents, newCalendarEvent)
 		}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// where warnings about skipped or approximated data are written, never to the output of the program
+var warningOutput io.Writer = os.Stderr
+
+var warningOutputMutex sync.Mutex
+
+// writes each warning once: the same problem usually repeats on many events of a file.
+// A warner lasts for a single parse or expansion, so that later ones warn again
+type warner struct {
+	writtenWarnings map[string]bool
+}
+
+func (w *warner) warn(format string, args ...any) {
+	warning := fmt.Sprintf("warning: "+format+"\n", args...)
+	if w.writtenWarnings[warning] {
+		return
+	}
+	if w.writtenWarnings == nil {
+		w.writtenWarnings = map[string]bool{}
+	}
+	w.writtenWarnings[warning] = true
+	warningOutputMutex.Lock()
+	defer warningOutputMutex.Unlock()
+	io.WriteString(warningOutput, warning)
+}
@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// a working window of a day, from FromHours:FromMinutes to ToHours:ToMinutes (24:00 is the end of the day)
+type TimeWindow struct {
+	FromHours   int
+	FromMinutes int
+	ToHours     int
+	ToMinutes   int
+}
+
+// working windows of each day of the week, indexed by time.Weekday. Days without windows are not worked
+type WeeklySchedule struct {
+	Windows [7][]TimeWindow
+}
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// same windows FromTime-ToTime every day, except weekends if skipWeekends
+func NewWeeklySchedule(fromTime, toTime string, skipWeekends bool) WeeklySchedule {
+	fromHours, fromMinutes := ParseTime(fromTime)
+	toHours, toMinutes := ParseTime(toTime)
+	weeklySchedule := WeeklySchedule{}
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if skipWeekends && (weekday == time.Saturday || weekday == time.Sunday) {
+			continue
+		}
+		weeklySchedule.Windows[weekday] = []TimeWindow{{fromHours, fromMinutes, toHours, toMinutes}}
+	}
+	return weeklySchedule
+}
+
+// parse a schedule in the form day[-day]=HH:MM-HH:MM[+HH:MM-HH:MM...][,...],
+// e.g. "mon-thu=08:30-12:30+13:30-17:00,fri=08:30-13:00". Windows of the same day are merged
+func ParseWeeklySchedule(schedule string) (WeeklySchedule, error) {
+	weeklySchedule := WeeklySchedule{}
+	for _, entry := range strings.Split(schedule, ",") {
+		days, windows, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found {
+			return WeeklySchedule{}, fmt.Errorf("bad schedule entry %q, expected day[-day]=HH:MM-HH:MM", entry)
+		}
+		weekdays, err := parseWeekdays(days)
+		if err != nil {
+			return WeeklySchedule{}, err
+		}
+		for _, window := range strings.Split(windows, "+") {
+			timeWindow, err := parseTimeWindow(window)
+			if err != nil {
+				return WeeklySchedule{}, err
+			}
+			for _, weekday := range weekdays {
+				weeklySchedule.Windows[weekday] = append(weeklySchedule.Windows[weekday], timeWindow)
+			}
+		}
+	}
+	for weekday := range weeklySchedule.Windows {
+		weeklySchedule.Windows[weekday] = mergeTimeWindows(weeklySchedule.Windows[weekday])
+	}
+	return weeklySchedule, nil
+}
+
+// windows of the weekday of date, in order
+func (weeklySchedule WeeklySchedule) GetWindows(date time.Time) []TimeWindow {
+	return weeklySchedule.Windows[date.Weekday()]
+}
+
+func (weeklySchedule WeeklySchedule) IsWorkingDay(date time.Time) bool {
+	return len(weeklySchedule.GetWindows(date)) > 0
+}
+
+// parse a day, e.g. "mon", or a range of days, e.g. "mon-fri" or "sat-sun"
+func parseWeekdays(days string) ([]time.Weekday, error) {
+	fromDay, toDay, isRange := strings.Cut(strings.ToLower(strings.TrimSpace(days)), "-")
+	if !isRange {
+		toDay = fromDay
+	}
+	fromIndex := slices.Index(weekdayNames, fromDay)
+	toIndex := slices.Index(weekdayNames, toDay)
+	if fromIndex < 0 || toIndex < 0 {
+		return nil, fmt.Errorf("bad days %q, expected e.g. mon or mon-fri", days)
+	}
+	weekdays := []time.Weekday{time.Weekday(fromIndex)}
+	for weekdayIndex := fromIndex; weekdayIndex != toIndex; {
+		weekdayIndex = (weekdayIndex + 1) % 7
+		weekdays = append(weekdays, time.Weekday(weekdayIndex))
+	}
+	return weekdays, nil
+}
+
+// parse a window in the form HH:MM-HH:MM
+func parseTimeWindow(window string) (TimeWindow, error) {
+	fromTime, toTime, found := strings.Cut(strings.TrimSpace(window), "-")
+	if !found || !isValidTime(fromTime) || !isValidTime(toTime) {
+		return TimeWindow{}, fmt.Errorf("bad window %q, expected HH:MM-HH:MM", window)
+	}
+	timeWindow := TimeWindow{}
+	timeWindow.FromHours, timeWindow.FromMinutes = ParseTime(fromTime)
+	timeWindow.ToHours, timeWindow.ToMinutes = ParseTime(toTime)
+	if timeWindow.getFromMinutes() >= timeWindow.getToMinutes() {
+		return TimeWindow{}, fmt.Errorf("bad window %q, end must be after start", window)
+	}
+	return timeWindow, nil
+}
+
+// HH:MM from 00:00 to 24:00
+func isValidTime(timeAsString string) bool {
+	if timeAsString == "24:00" {
+		return true
+	}
+	_, err := time.Parse("15:04", timeAsString)
+	return err == nil
+}
+
+func (timeWindow TimeWindow) getFromMinutes() int {
+	return timeWindow.FromHours*60 + timeWindow.FromMinutes
+}
+
+func (timeWindow TimeWindow) getToMinutes() int {
+	return timeWindow.ToHours*60 + timeWindow.ToMinutes
+}
+
+// sort windows and merge the overlapping ones
+func mergeTimeWindows(timeWindows []TimeWindow) []TimeWindow {
+	slices.SortFunc(timeWindows, func(a, b TimeWindow) int {
+		return a.getFromMinutes() - b.getFromMinutes()
+	})
+	mergedWindows := []TimeWindow{}
+	for _, timeWindow := range timeWindows {
+		lastIndex := len(mergedWindows) - 1
+		if lastIndex >= 0 && timeWindow.getFromMinutes() <= mergedWindows[lastIndex].getToMinutes() {
+			if timeWindow.getToMinutes() > mergedWindows[lastIndex].getToMinutes() {
+				mergedWindows[lastIndex].ToHours, mergedWindows[lastIndex].ToMinutes = timeWindow.ToHours, timeWindow.ToMinutes
+			}
+			continue
+		}
+		mergedWindows = append(mergedWindows, timeWindow)
+	}
+	return mergedWindows
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseWeeklySchedule(t *testing.T) {
+	testCases := []struct {
+		schedule        string
+		expectedWindows [7]string // from Sunday to Saturday
+	}{
+		{"mon-thu=08:30-17:00,fri=08:30-13:00",
+			[7]string{"", "08:30-17:00", "08:30-17:00", "08:30-17:00", "08:30-17:00", "08:30-13:00", ""}},
+		{"mon-fri=08:00-12:00+13:00-17:00, sat=09:00-12:00",
+			[7]string{"", "08:00-12:00 13:00-17:00", "08:00-12:00 13:00-17:00", "08:00-12:00 13:00-17:00",
+				"08:00-12:00 13:00-17:00", "08:00-12:00 13:00-17:00", "09:00-12:00"}},
+		// ranges across the end of the week, overlapping windows are merged
+		{"fri-mon=14:00-18:00,Mon=09:00-15:00,sun=20:00-24:00",
+			[7]string{"14:00-18:00 20:00-24:00", "09:00-18:00", "", "", "", "14:00-18:00", "14:00-18:00"}},
+	}
+	for _, testCase := range testCases {
+		weeklySchedule, err := ParseWeeklySchedule(testCase.schedule)
+		if err != nil {
+			t.Errorf("Error while parsing %q: %v", testCase.schedule, err)
+			continue
+		}
+		for weekday, timeWindows := range weeklySchedule.Windows {
+			windows := ""
+			for windowIndex, timeWindow := range timeWindows {
+				if windowIndex > 0 {
+					windows += " "
+				}
+				windows += fmt.Sprintf("%02d:%02d-%02d:%02d", timeWindow.FromHours, timeWindow.FromMinutes,
+					timeWindow.ToHours, timeWindow.ToMinutes)
+			}
+			if windows != testCase.expectedWindows[weekday] {
+				t.Errorf("%q: wrong windows on %v: %q, expected %q", testCase.schedule, time.Weekday(weekday),
+					windows, testCase.expectedWindows[weekday])
+			}
+		}
+	}
+
+	badSchedules := []string{"", "mon", "xyz=09:00-10:00", "mon-=09:00-10:00", "mon=09:00",
+		"mon=10:00-09:00", "mon=09:00-25:00", "mon=09:00-10:00+"}
+	for _, badSchedule := range badSchedules {
+		if _, err := ParseWeeklySchedule(badSchedule); err == nil {
+			t.Errorf("No error while parsing %q", badSchedule)
+		}
+	}
+}
+
+func TestFreeSlotsCoreWithWeeklySchedule(t *testing.T) {
+	weeklySchedule, _ := ParseWeeklySchedule("mon-thu=08:30-12:30+13:30-17:00,fri=08:30-13:00,sat=09:00-11:00")
+	// busy on Thursday 09:00-10:00 and 12:00-14:00
+	dailyAgenda, _ := ParseDailyAgendaInLocation("d2025-12-11,m60,s9,aX--YY", time.UTC)
+	var output bytes.Buffer
+	freeSlotsCoreAlgorithm := FreeSlotsCoreAlgorithm{
+		NoDays:      4,
+		MinDuration: 60,
+		FromTime:    "09:00",
+		ToTime:      "18:00",
+		Format:      "plain",
+		StartDate:   time.Date(2025, time.December, 11, 0, 0, 0, 0, time.UTC),
+		Schedule:    &weeklySchedule,
+		Output:      &output,
+	}
+	if err := freeSlotsCoreAlgorithm.FreeSlotsCore([]DailyAgenda{dailyAgenda}); err != nil {
+		t.Errorf("Error while getting free slots: %v", err)
+		return
+	}
+	// 08:30-09:00 is too short, Sunday is not worked
+	expectedOutput := "11 Dec 2025: 10:00-12:00 UTC, 14:00-17:00 UTC\n" +
+		"12 Dec 2025: 08:30-13:00 UTC\n" +
+		"13 Dec 2025: 09:00-11:00 UTC\n"
+	if output.String() != expectedOutput {
+		t.Errorf("Unexpected output: %q", output.String())
+	}
+}